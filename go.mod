@@ -6,12 +6,17 @@ require (
 	github.com/coldzerofear/vgpu-manager v0.4.2-0.20250723152154-8cfabeb9a71a
 	github.com/onsi/ginkgo/v2 v2.21.0
 	github.com/onsi/gomega v1.35.1
+	github.com/spf13/cobra v1.8.1
 	k8s.io/api v0.32.6
 	k8s.io/apimachinery v0.32.6
 	k8s.io/client-go v0.32.6
 	k8s.io/component-base v0.32.6
+	k8s.io/component-helpers v0.32.6
 	k8s.io/klog/v2 v2.130.1
+	k8s.io/kube-scheduler v0.32.6
 	k8s.io/kubernetes v1.32.6
+	k8s.io/utils v0.0.0-20241104100929-3ea5e8cea738
+	sigs.k8s.io/yaml v1.4.0
 )
 
 require (
@@ -72,7 +77,6 @@ require (
 	github.com/prometheus/common v0.55.0 // indirect
 	github.com/prometheus/procfs v0.15.1 // indirect
 	github.com/sirupsen/logrus v1.9.3 // indirect
-	github.com/spf13/cobra v1.8.1 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	github.com/stoewer/go-strcase v1.3.0 // indirect
 	github.com/x448/float16 v0.8.4 // indirect
@@ -112,20 +116,16 @@ require (
 	k8s.io/apiextensions-apiserver v0.32.6 // indirect
 	k8s.io/apiserver v0.32.6 // indirect
 	k8s.io/cloud-provider v0.32.6 // indirect
-	k8s.io/component-helpers v0.32.6 // indirect
 	k8s.io/controller-manager v0.32.6 // indirect
 	k8s.io/csi-translation-lib v0.0.0 // indirect
 	k8s.io/dynamic-resource-allocation v0.0.0 // indirect
 	k8s.io/kms v0.32.6 // indirect
 	k8s.io/kube-openapi v0.0.0-20241105132330-32ad38e42d3f // indirect
-	k8s.io/kube-scheduler v0.32.6 // indirect
 	k8s.io/kubectl v0.32.6 // indirect
 	k8s.io/kubelet v0.32.6 // indirect
-	k8s.io/utils v0.0.0-20241104100929-3ea5e8cea738 // indirect
 	sigs.k8s.io/apiserver-network-proxy/konnectivity-client v0.31.0 // indirect
 	sigs.k8s.io/json v0.0.0-20241010143419-9aa6b5e7a4b3 // indirect
 	sigs.k8s.io/structured-merge-diff/v4 v4.4.2 // indirect
-	sigs.k8s.io/yaml v1.4.0 // indirect
 )
 
 replace (
@@ -4,6 +4,7 @@ import (
 	"os"
 
 	"github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/plugin"
+	"github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/simulator"
 	"k8s.io/component-base/cli"
 	"k8s.io/kubernetes/cmd/kube-scheduler/app"
 )
@@ -12,6 +13,7 @@ func main() {
 	command := app.NewSchedulerCommand(
 		app.WithPlugin(plugin.Name, plugin.New),
 	)
+	command.AddCommand(simulator.NewSimulateCommand())
 	code := cli.Run(command)
 	os.Exit(code)
 }
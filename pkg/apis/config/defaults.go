@@ -0,0 +1,55 @@
+package config
+
+import (
+	"time"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	DefaultNodePolicy                      = string(util.NonePolicy)
+	DefaultDevicePolicy                    = string(util.NonePolicy)
+	DefaultTopologyBonusPercent      int64 = 10
+	DefaultBindThrottleInterval            = 30 * time.Millisecond
+	DefaultPodGroupWaitTimeout             = 60 * time.Second
+	DefaultGPUFlappingWindow               = 10 * time.Minute
+	DefaultGPUFlappingPenaltyPercent int64 = 20
+	DefaultAllocationTimeout               = 5 * time.Minute
+	DefaultNodeScoringWeight         int64 = 1
+)
+
+// SetDefaults sets the default parameters for VGPUSchedulerPlugin.
+func SetDefaults(args *VGPUSchedulerPluginArgs) {
+	if args.DefaultNodePolicy == nil {
+		args.DefaultNodePolicy = ptr.To(DefaultNodePolicy)
+	}
+	if args.DefaultDevicePolicy == nil {
+		args.DefaultDevicePolicy = ptr.To(DefaultDevicePolicy)
+	}
+	if args.NodeScoringWeights == nil {
+		args.NodeScoringWeights = &NodeScoringWeights{
+			Cores:  DefaultNodeScoringWeight,
+			Memory: DefaultNodeScoringWeight,
+		}
+	}
+	if args.TopologyBonusPercent == nil {
+		args.TopologyBonusPercent = ptr.To(DefaultTopologyBonusPercent)
+	}
+	if args.BindThrottleInterval == nil {
+		args.BindThrottleInterval = &metav1.Duration{Duration: DefaultBindThrottleInterval}
+	}
+	if args.PodGroupWaitTimeout == nil {
+		args.PodGroupWaitTimeout = &metav1.Duration{Duration: DefaultPodGroupWaitTimeout}
+	}
+	if args.GPUFlappingWindow == nil {
+		args.GPUFlappingWindow = &metav1.Duration{Duration: DefaultGPUFlappingWindow}
+	}
+	if args.GPUFlappingPenaltyPercent == nil {
+		args.GPUFlappingPenaltyPercent = ptr.To(DefaultGPUFlappingPenaltyPercent)
+	}
+	if args.AllocationTimeout == nil {
+		args.AllocationTimeout = &metav1.Duration{Duration: DefaultAllocationTimeout}
+	}
+}
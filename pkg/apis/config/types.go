@@ -0,0 +1,110 @@
+// Package config holds the args of the VGPUSchedulerPlugin. They are decoded from the raw
+// args of the plugin's pluginConfig entry and are not registered in the scheduler's scheme.
+package config
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VGPUSchedulerPluginArgs holds arguments used to configure the VGPUSchedulerPlugin.
+// It is decoded from the plugin's entry in the KubeSchedulerConfiguration pluginConfig.
+type VGPUSchedulerPluginArgs struct {
+	// DefaultNodePolicy is the node scheduling policy used when the pod does not
+	// specify one by annotation, one of none / binpack / spread / least-fragmentation /
+	// memory-binpack / prefer-idle / weighted or a policy registered by an out-of-tree build.
+	// Defaults to none.
+	DefaultNodePolicy *string `json:"defaultNodePolicy,omitempty"`
+	// DefaultDevicePolicy is the device scheduling policy used when the pod does not
+	// specify one by annotation, one of none / binpack / spread / prefer-idle.
+	// Defaults to none.
+	DefaultDevicePolicy *string `json:"defaultDevicePolicy,omitempty"`
+	// NodeScoringWeights are the weights of the GPU cores and memory in the weighted node policy.
+	// Defaults to 1 for both.
+	NodeScoringWeights *NodeScoringWeights `json:"nodeScoringWeights,omitempty"`
+	// TopologyBonusPercent is the percentage of the node score added to (or taken from)
+	// nodes with (or without) GPU topology, for pods that use the link topology mode.
+	// Defaults to 10.
+	TopologyBonusPercent *int64 `json:"topologyBonusPercent,omitempty"`
+	// BindThrottleInterval is the maximum time the binding of a vGPU pod waits for the device
+	// plugin to pick up the previous vGPU pod bound to the same node.
+	// Defaults to 30ms.
+	BindThrottleInterval *metav1.Duration `json:"bindThrottleInterval,omitempty"`
+	// EnableBind makes the plugin bind the pods itself, otherwise the plugin only patches the
+	// vGPU metadata of the pods in PreBind and leaves the binding to the next bind plugin.
+	EnableBind bool `json:"enableBind,omitempty"`
+	// PodGroupWaitTimeout is the maximum time the pods of a pod group wait in the Permit
+	// phase for the group to reach its min member count before the group is rejected.
+	// Defaults to 60s.
+	PodGroupWaitTimeout *metav1.Duration `json:"podGroupWaitTimeout,omitempty"`
+	// GPUFlappingWindow is how long a health transition of a GPU of a node counts
+	// towards the flapping penalty of the node.
+	// Defaults to 10m.
+	GPUFlappingWindow *metav1.Duration `json:"gpuFlappingWindow,omitempty"`
+	// GPUFlappingPenaltyPercent is the percentage of the node score taken from nodes
+	// whose GPUs changed health within the flapping window, 0 disables the penalty.
+	// Defaults to 20.
+	GPUFlappingPenaltyPercent *int64 `json:"gpuFlappingPenaltyPercent,omitempty"`
+	// AllocationTimeout is the maximum time a bound vGPU pod stays in the allocating phase
+	// waiting for the device plugin, the pod is marked failed afterwards, 0 disables the check.
+	// Defaults to 5m.
+	AllocationTimeout *metav1.Duration `json:"allocationTimeout,omitempty"`
+	// DeleteTimedOutPods deletes the pods marked failed by the allocation timeout so that
+	// their controller recreates them, pods without a controller are never deleted.
+	DeleteTimedOutPods bool `json:"deleteTimedOutPods,omitempty"`
+	// Quotas limit the vGPU resources allocated to the pods of namespaces or label selectors.
+	Quotas []VGPUQuota `json:"quotas,omitempty"`
+	// ElasticQuotaConfigMap is the namespace/name of the ConfigMap holding the elastic quotas
+	// under the "elasticQuotas" key, elastic quotas are disabled when empty.
+	ElasticQuotaConfigMap string `json:"elasticQuotaConfigMap,omitempty"`
+	// DebugBindAddress is the address of the read-only HTTP endpoint exposing the plugin's
+	// per-node device view under /debug/vgpu/nodes, the endpoint is disabled when empty.
+	DebugBindAddress string `json:"debugBindAddress,omitempty"`
+	// FeatureGates is a map of feature names to bools that enable or disable plugin features.
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+}
+
+// NodeScoringWeights are the weights of the resources in the weighted node policy.
+type NodeScoringWeights struct {
+	// Cores is the weight of the allocated GPU cores.
+	Cores int64 `json:"cores"`
+	// Memory is the weight of the allocated GPU memory.
+	Memory int64 `json:"memory"`
+}
+
+// VGPUQuota limits the total vGPU resources allocated to the pods it applies to.
+// A pod is subject to the quota when it is in one of the namespaces (any namespace
+// when empty) and matches the selector (any pod when not set).
+type VGPUQuota struct {
+	// Name identifies the quota in events and logs.
+	Name string `json:"name"`
+	// Namespaces are the namespaces whose pods share the quota.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Selector selects the pods sharing the quota by labels.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	// VGPUResourceList is the maximum vGPU resources of the pods sharing the quota.
+	VGPUResourceList `json:",inline"`
+}
+
+// VGPUResourceList is an amount of vGPU resources, a resource is unlimited when not set.
+type VGPUResourceList struct {
+	// Number is the number of vGPU devices.
+	Number *int64 `json:"number,omitempty"`
+	// Cores is the sum of vGPU cores.
+	Cores *int64 `json:"cores,omitempty"`
+	// Memory is the sum of vGPU memory, in the units of the vGPU memory requests.
+	Memory *int64 `json:"memory,omitempty"`
+}
+
+// ElasticQuota guarantees the Min vGPU resources to the pods of its namespaces and lets them
+// borrow the idle guaranteed resources of other elastic quotas up to Max. Borrowed resources
+// are reclaimed by preemption when their owner needs them.
+type ElasticQuota struct {
+	// Name identifies the quota in events and logs.
+	Name string `json:"name"`
+	// Namespaces are the namespaces whose pods share the quota.
+	Namespaces []string `json:"namespaces"`
+	// Min is the guaranteed vGPU resources, a resource is not guaranteed when not set.
+	Min VGPUResourceList `json:"min,omitempty"`
+	// Max is the maximum vGPU resources including the borrowed ones.
+	Max VGPUResourceList `json:"max,omitempty"`
+}
@@ -0,0 +1,163 @@
+package validation
+
+import (
+	"net"
+	"strings"
+
+	pluginconfig "github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/apis/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ValidateVGPUSchedulerPluginArgs validates that VGPUSchedulerPluginArgs are correct.
+// The node and device policies are the names of the node and device scheduling policies of the plugin.
+func ValidateVGPUSchedulerPluginArgs(path *field.Path, args *pluginconfig.VGPUSchedulerPluginArgs, nodePolicies, devicePolicies []string) error {
+	var allErrs field.ErrorList
+	if args.DefaultNodePolicy != nil {
+		policy := strings.ToLower(*args.DefaultNodePolicy)
+		if !sets.New(nodePolicies...).Has(policy) {
+			allErrs = append(allErrs, field.NotSupported(path.Child("defaultNodePolicy"),
+				*args.DefaultNodePolicy, nodePolicies))
+		}
+	}
+	if args.DefaultDevicePolicy != nil {
+		policy := strings.ToLower(*args.DefaultDevicePolicy)
+		if !sets.New(devicePolicies...).Has(policy) {
+			allErrs = append(allErrs, field.NotSupported(path.Child("defaultDevicePolicy"),
+				*args.DefaultDevicePolicy, devicePolicies))
+		}
+	}
+	if weights := args.NodeScoringWeights; weights != nil {
+		weightsPath := path.Child("nodeScoringWeights")
+		if weights.Cores < 0 {
+			allErrs = append(allErrs, field.Invalid(weightsPath.Child("cores"), weights.Cores, "must not be negative"))
+		}
+		if weights.Memory < 0 {
+			allErrs = append(allErrs, field.Invalid(weightsPath.Child("memory"), weights.Memory, "must not be negative"))
+		}
+		if weights.Cores+weights.Memory <= 0 {
+			allErrs = append(allErrs, field.Invalid(weightsPath, weights, "at least one weight must be greater than 0"))
+		}
+	}
+	if args.TopologyBonusPercent != nil {
+		if percent := *args.TopologyBonusPercent; percent < 0 || percent > 100 {
+			allErrs = append(allErrs, field.Invalid(path.Child("topologyBonusPercent"),
+				percent, "must be in the range [0, 100]"))
+		}
+	}
+	if args.BindThrottleInterval != nil && args.BindThrottleInterval.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("bindThrottleInterval"),
+			args.BindThrottleInterval.Duration.String(), "must not be negative"))
+	}
+	if args.PodGroupWaitTimeout != nil && args.PodGroupWaitTimeout.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("podGroupWaitTimeout"),
+			args.PodGroupWaitTimeout.Duration.String(), "must be greater than 0"))
+	}
+	if args.GPUFlappingWindow != nil && args.GPUFlappingWindow.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("gpuFlappingWindow"),
+			args.GPUFlappingWindow.Duration.String(), "must be greater than 0"))
+	}
+	if args.GPUFlappingPenaltyPercent != nil {
+		if percent := *args.GPUFlappingPenaltyPercent; percent < 0 || percent > 100 {
+			allErrs = append(allErrs, field.Invalid(path.Child("gpuFlappingPenaltyPercent"),
+				percent, "must be in the range [0, 100]"))
+		}
+	}
+	if args.AllocationTimeout != nil && args.AllocationTimeout.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("allocationTimeout"),
+			args.AllocationTimeout.Duration.String(), "must not be negative"))
+	}
+	allErrs = append(allErrs, validateQuotas(path.Child("quotas"), args.Quotas)...)
+	if len(args.ElasticQuotaConfigMap) > 0 {
+		namespace, name, err := cache.SplitMetaNamespaceKey(args.ElasticQuotaConfigMap)
+		if err != nil || len(namespace) == 0 || len(name) == 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child("elasticQuotaConfigMap"),
+				args.ElasticQuotaConfigMap, "must be in the form namespace/name"))
+		}
+	}
+	if len(args.DebugBindAddress) > 0 {
+		if _, _, err := net.SplitHostPort(args.DebugBindAddress); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("debugBindAddress"),
+				args.DebugBindAddress, "must be in the form host:port"))
+		}
+	}
+	return allErrs.ToAggregate()
+}
+
+func validateQuotas(path *field.Path, quotas []pluginconfig.VGPUQuota) field.ErrorList {
+	var allErrs field.ErrorList
+	names := sets.New[string]()
+	for i, quota := range quotas {
+		quotaPath := path.Index(i)
+		if len(quota.Name) == 0 {
+			allErrs = append(allErrs, field.Required(quotaPath.Child("name"), ""))
+		} else if names.Has(quota.Name) {
+			allErrs = append(allErrs, field.Duplicate(quotaPath.Child("name"), quota.Name))
+		}
+		names.Insert(quota.Name)
+		if quota.Selector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(quota.Selector); err != nil {
+				allErrs = append(allErrs, field.Invalid(quotaPath.Child("selector"), quota.Selector, err.Error()))
+			}
+		}
+		allErrs = append(allErrs, validateResourceList(quotaPath, quota.VGPUResourceList)...)
+	}
+	return allErrs
+}
+
+func resourceListItems(list pluginconfig.VGPUResourceList) []struct {
+	name  string
+	value *int64
+} {
+	return []struct {
+		name  string
+		value *int64
+	}{{"number", list.Number}, {"cores", list.Cores}, {"memory", list.Memory}}
+}
+
+func validateResourceList(path *field.Path, list pluginconfig.VGPUResourceList) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, item := range resourceListItems(list) {
+		if item.value != nil && *item.value < 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child(item.name), *item.value, "must not be negative"))
+		}
+	}
+	return allErrs
+}
+
+// ValidateElasticQuotas validates that the elastic quotas read from the ConfigMap are correct.
+func ValidateElasticQuotas(path *field.Path, quotas []pluginconfig.ElasticQuota) error {
+	var allErrs field.ErrorList
+	names := sets.New[string]()
+	namespaces := sets.New[string]()
+	for i, quota := range quotas {
+		quotaPath := path.Index(i)
+		if len(quota.Name) == 0 {
+			allErrs = append(allErrs, field.Required(quotaPath.Child("name"), ""))
+		} else if names.Has(quota.Name) {
+			allErrs = append(allErrs, field.Duplicate(quotaPath.Child("name"), quota.Name))
+		}
+		names.Insert(quota.Name)
+		if len(quota.Namespaces) == 0 {
+			allErrs = append(allErrs, field.Required(quotaPath.Child("namespaces"), ""))
+		}
+		for j, namespace := range quota.Namespaces {
+			if namespaces.Has(namespace) {
+				allErrs = append(allErrs, field.Duplicate(quotaPath.Child("namespaces").Index(j), namespace))
+			}
+			namespaces.Insert(namespace)
+		}
+		allErrs = append(allErrs, validateResourceList(quotaPath.Child("min"), quota.Min)...)
+		allErrs = append(allErrs, validateResourceList(quotaPath.Child("max"), quota.Max)...)
+		maxItems := resourceListItems(quota.Max)
+		for k, minItem := range resourceListItems(quota.Min) {
+			if maxItem := maxItems[k]; minItem.value != nil && maxItem.value != nil && *minItem.value > *maxItem.value {
+				allErrs = append(allErrs, field.Invalid(quotaPath.Child("min", minItem.name),
+					*minItem.value, "must not be greater than max"))
+			}
+		}
+	}
+	return allErrs.ToAggregate()
+}
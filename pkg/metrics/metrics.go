@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// VGPUSchedulerSubsystem - subsystem name used by the vGPU scheduler plugin.
+const VGPUSchedulerSubsystem = "vgpu_scheduler_plugin"
+
+// Filter rejection reasons.
+const (
+	RejectReasonNoGPU              = "no_gpu"
+	RejectReasonInsufficientNumber = "insufficient_number"
+	RejectReasonNodeFilter         = "node_filter"
+	RejectReasonDeviceAllocation   = "device_allocation"
+)
+
+// Bind failure stages.
+const (
+	BindStagePatch    = "patch"
+	BindStageBind     = "bind"
+	BindStageRollback = "rollback"
+)
+
+var (
+	FilterRejections = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      VGPUSchedulerSubsystem,
+			Name:           "filter_rejections_total",
+			Help:           "Number of nodes rejected by the vGPU Filter, by reason.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"reason"})
+	AllocationDuration = metrics.NewHistogram(
+		&metrics.HistogramOpts{
+			Subsystem:      VGPUSchedulerSubsystem,
+			Name:           "allocation_duration_seconds",
+			Help:           "Latency of device allocation attempts on a node in seconds.",
+			Buckets:        metrics.ExponentialBuckets(0.00001, 2, 15),
+			StabilityLevel: metrics.ALPHA,
+		})
+	BindWaitDuration = metrics.NewHistogram(
+		&metrics.HistogramOpts{
+			Subsystem:      VGPUSchedulerSubsystem,
+			Name:           "bind_wait_duration_seconds",
+			Help:           "Time a vGPU pod binding waited for the previous binding on the same node in seconds.",
+			Buckets:        metrics.ExponentialBuckets(0.001, 2, 15),
+			StabilityLevel: metrics.ALPHA,
+		})
+	PatchRetries = metrics.NewCounter(
+		&metrics.CounterOpts{
+			Subsystem:      VGPUSchedulerSubsystem,
+			Name:           "patch_retries_total",
+			Help:           "Number of retried pod metadata patches.",
+			StabilityLevel: metrics.ALPHA,
+		})
+	BindFailures = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      VGPUSchedulerSubsystem,
+			Name:           "bind_failures_total",
+			Help:           "Number of failed pod bindings, by stage.",
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"stage"})
+	SelectedNodeScore = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem:      VGPUSchedulerSubsystem,
+			Name:           "selected_node_score",
+			Help:           "Normalized score of the node selected for vGPU pods, by node scheduling policy.",
+			Buckets:        metrics.LinearBuckets(0, 10, 11),
+			StabilityLevel: metrics.ALPHA,
+		}, []string{"policy"})
+	AllocationTimeouts = metrics.NewCounter(
+		&metrics.CounterOpts{
+			Subsystem:      VGPUSchedulerSubsystem,
+			Name:           "allocation_timeouts_total",
+			Help:           "Number of vGPU pods marked failed after staying in the allocating phase past the timeout.",
+			StabilityLevel: metrics.ALPHA,
+		})
+
+	metricsList = []metrics.Registerable{
+		FilterRejections,
+		AllocationDuration,
+		BindWaitDuration,
+		PatchRetries,
+		BindFailures,
+		SelectedNodeScore,
+		AllocationTimeouts,
+	}
+)
+
+var registerMetrics sync.Once
+
+// Register all metrics with the kube-scheduler legacy registry.
+func Register() {
+	registerMetrics.Do(func() {
+		for _, metric := range metricsList {
+			legacyregistry.MustRegister(metric)
+		}
+	})
+}
+
+// SinceInSeconds gets the time since the specified start in seconds.
+func SinceInSeconds(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
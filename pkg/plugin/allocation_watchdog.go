@@ -0,0 +1,97 @@
+package plugin
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/metrics"
+	"github.com/coldzerofear/vgpu-manager/pkg/client"
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/klog/v2"
+)
+
+const maxAllocationCheckInterval = 30 * time.Second
+
+// allocationWatchdog marks failed the vGPU pods allocating for longer than the timeout.
+type allocationWatchdog struct {
+	client     kubernetes.Interface
+	podLister  listerv1.PodLister
+	recorder   events.EventRecorder
+	timeout    time.Duration
+	deletePods bool
+	now        func() time.Time
+}
+
+func newAllocationWatchdog(client kubernetes.Interface, podLister listerv1.PodLister,
+	recorder events.EventRecorder, timeout time.Duration, deletePods bool) *allocationWatchdog {
+	return &allocationWatchdog{
+		client:     client,
+		podLister:  podLister,
+		recorder:   recorder,
+		timeout:    timeout,
+		deletePods: deletePods,
+		now:        time.Now,
+	}
+}
+
+func (w *allocationWatchdog) start(ctx context.Context) {
+	interval := min(w.timeout/2, maxAllocationCheckInterval)
+	go wait.UntilWithContext(ctx, w.check, interval)
+}
+
+// check rolls back the pods allocating for longer than the timeout.
+func (w *allocationWatchdog) check(ctx context.Context) {
+	logger := klog.FromContext(ctx)
+	selector := labels.SelectorFromSet(labels.Set{util.PodAssignedPhaseLabel: string(util.AssignPhaseAllocating)})
+	pods, err := w.podLister.List(selector)
+	if err != nil {
+		logger.Error(err, "listing allocating pods failed")
+		return
+	}
+	for _, pod := range pods {
+		if !w.timedOut(pod) {
+			continue
+		}
+		logger.Info("vGPU allocation timed out", "pod", klog.KObj(pod), "node", pod.Spec.NodeName, "timeout", w.timeout)
+		if err = client.PatchPodAllocationFailed(w.client, pod.DeepCopy()); err != nil {
+			logger.Error(err, "marking the allocation of pod failed", "pod", klog.KObj(pod))
+			continue
+		}
+		metrics.AllocationTimeouts.Inc()
+		w.recorder.Eventf(pod, nil, v1.EventTypeWarning, "VGPUAllocationTimeout", "Allocating",
+			"vGPU allocation on node %s did not complete within %s", pod.Spec.NodeName, w.timeout)
+		if w.deletePods && metav1.GetControllerOf(pod) != nil {
+			w.deletePod(ctx, pod)
+		}
+	}
+}
+
+func (w *allocationWatchdog) timedOut(pod *v1.Pod) bool {
+	if len(pod.Spec.NodeName) == 0 || pod.DeletionTimestamp != nil ||
+		pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+		return false
+	}
+	predicateTime, err := strconv.ParseInt(pod.Annotations[util.PodPredicateTimeAnnotation], 10, 64)
+	if err != nil {
+		return false
+	}
+	return w.now().Sub(time.Unix(0, predicateTime)) > w.timeout
+}
+
+func (w *allocationWatchdog) deletePod(ctx context.Context, pod *v1.Pod) {
+	err := w.client.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{
+		Preconditions: metav1.NewUIDPreconditions(string(pod.UID)),
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.FromContext(ctx).Error(err, "deleting pod with timed out allocation failed", "pod", klog.KObj(pod))
+	}
+}
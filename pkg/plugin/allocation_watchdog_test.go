@@ -0,0 +1,137 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes/fake"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("VGPUSchedulerPlugin allocation watchdog", func() {
+	var (
+		ctx      context.Context
+		now      time.Time
+		fakeCli  *fake.Clientset
+		indexer  cache.Indexer
+		recorder *events.FakeRecorder
+		watchdog *allocationWatchdog
+	)
+
+	newAllocatingPod := func(name, nodeName string, allocatingFor time.Duration, controlled bool) *v1.Pod {
+		pod := &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				UID:       uuid.NewUUID(),
+				Labels:    map[string]string{util.PodAssignedPhaseLabel: string(util.AssignPhaseAllocating)},
+				Annotations: map[string]string{
+					util.PodPredicateTimeAnnotation: fmt.Sprintf("%d", now.Add(-allocatingFor).UnixNano()),
+				},
+			},
+			Spec: v1.PodSpec{NodeName: nodeName},
+		}
+		if controlled {
+			pod.OwnerReferences = []metav1.OwnerReference{{
+				APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "test-rs", UID: uuid.NewUUID(), Controller: ptr.To(true),
+			}}
+		}
+		Expect(indexer.Add(pod)).To(Succeed())
+		_, err := fakeCli.CoreV1().Pods(pod.Namespace).Create(ctx, pod, metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		return pod
+	}
+
+	getPod := func(pod *v1.Pod) (*v1.Pod, error) {
+		return fakeCli.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		now = time.Now()
+		fakeCli = fake.NewClientset()
+		indexer = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		recorder = events.NewFakeRecorder(10)
+		watchdog = newAllocationWatchdog(fakeCli, listerv1.NewPodLister(indexer), recorder, 5*time.Minute, false)
+		watchdog.now = func() time.Time { return now }
+	})
+
+	It("should mark failed the pods allocating past the timeout", func() {
+		stuckPod := newAllocatingPod("stuck", "node1", 10*time.Minute, true)
+		freshPod := newAllocatingPod("fresh", "node1", time.Minute, true)
+		unboundPod := newAllocatingPod("unbound", "", 10*time.Minute, true)
+		watchdog.check(ctx)
+
+		pod, err := getPod(stuckPod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Labels[util.PodAssignedPhaseLabel]).To(Equal(string(util.AssignPhaseFailed)))
+		Expect(recorder.Events).To(HaveLen(1))
+		Expect(<-recorder.Events).To(ContainSubstring("VGPUAllocationTimeout"))
+		for _, notStuck := range []*v1.Pod{freshPod, unboundPod} {
+			pod, err = getPod(notStuck)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Labels[util.PodAssignedPhaseLabel]).To(Equal(string(util.AssignPhaseAllocating)))
+		}
+	})
+
+	It("should release the devices of the timed out pods", func() {
+		devCache := newDeviceCache()
+		node := &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "node1",
+				Annotations: map[string]string{
+					util.NodeDeviceRegisterAnnotation: `[{"id":0,"uuid":"GPU-0","core":100,"memory":10240,"number":1,"healthy":true}]`,
+				},
+			},
+		}
+		devCache.updateNode(node)
+		stuckPod := newAllocatingPod("stuck", "node1", 10*time.Minute, false)
+		stuckPod.Annotations[util.PodVGPUPreAllocAnnotation] = "default[0_GPU-0_100_10240]"
+		stuckPod.Spec.Containers = []v1.Container{{
+			Name: "default",
+			Resources: v1.ResourceRequirements{
+				Limits: v1.ResourceList{util.VGPUNumberResourceName: resource.MustParse("1")},
+			},
+		}}
+		devCache.updatePod(stuckPod)
+		info, err := devCache.Snapshot(node)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.GetAvailableMemory()).To(BeZero())
+		Expect(podRequestedUsage(stuckPod).number).To(Equal(int64(1)))
+
+		watchdog.check(ctx)
+		Expect(stuckPod.Labels[util.PodAssignedPhaseLabel]).To(Equal(string(util.AssignPhaseAllocating)))
+		failedPod, err := getPod(stuckPod)
+		Expect(err).NotTo(HaveOccurred())
+		devCache.updatePod(failedPod)
+		info, err = devCache.Snapshot(node)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.GetAvailableMemory()).To(Equal(10240))
+		Expect(info.GetAvailableNumber()).To(Equal(1))
+		Expect(podRequestedUsage(failedPod)).To(Equal(vgpuUsage{}))
+	})
+
+	It("should delete the timed out pods with a controller when enabled", func() {
+		watchdog.deletePods = true
+		controlledPod := newAllocatingPod("controlled", "node1", 10*time.Minute, true)
+		barePod := newAllocatingPod("bare", "node1", 10*time.Minute, false)
+		watchdog.check(ctx)
+
+		_, err := getPod(controlledPod)
+		Expect(err).To(HaveOccurred())
+		pod, err := getPod(barePod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Labels[util.PodAssignedPhaseLabel]).To(Equal(string(util.AssignPhaseFailed)))
+	})
+})
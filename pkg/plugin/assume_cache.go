@@ -0,0 +1,97 @@
+package plugin
+
+import (
+	"sync"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// assumeCache records the device pre-allocations made in the Reserve phase,
+// until the pod informer observes the pre-allocation annotation patched by Bind.
+type assumeCache struct {
+	mu   sync.RWMutex
+	pods map[types.UID]*v1.Pod
+}
+
+func newAssumeCache() *assumeCache {
+	return &assumeCache{
+		pods: make(map[types.UID]*v1.Pod),
+	}
+}
+
+// Assume records the pre-allocated devices of pod on the node.
+func (c *assumeCache) Assume(pod *v1.Pod, nodeName, preAllocate string) {
+	assumed := pod.DeepCopy()
+	util.InsertAnnotation(assumed, util.PodPredicateNodeAnnotation, nodeName)
+	util.InsertAnnotation(assumed, util.PodVGPUPreAllocAnnotation, preAllocate)
+	util.InsertAnnotation(assumed, util.PodVGPURealAllocAnnotation, "")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pods[pod.UID] = assumed
+}
+
+// Forget releases the pre-allocated devices of the pod.
+func (c *assumeCache) Forget(uid types.UID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pods, uid)
+}
+
+// IsAssumed returns whether the pod has devices pre-allocated in the cache.
+func (c *assumeCache) IsAssumed(uid types.UID) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.pods[uid]
+	return ok
+}
+
+// Merge replaces the pods known to the cache with their assumed version,
+// and appends the assumed pods that are not in the list.
+func (c *assumeCache) Merge(pods []*v1.Pod) []*v1.Pod {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.pods) == 0 {
+		return pods
+	}
+	merged := make([]*v1.Pod, 0, len(pods)+len(c.pods))
+	for _, pod := range pods {
+		if _, ok := c.pods[pod.UID]; !ok {
+			merged = append(merged, pod)
+		}
+	}
+	for _, pod := range c.pods {
+		merged = append(merged, pod)
+	}
+	return merged
+}
+
+// PodsOnNode returns the assumed pods pre-allocated on the node.
+func (c *assumeCache) PodsOnNode(nodeName string) []*v1.Pod {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var pods []*v1.Pod
+	for _, pod := range c.pods {
+		if pod.Annotations[util.PodPredicateNodeAnnotation] == nodeName {
+			pods = append(pods, pod)
+		}
+	}
+	return pods
+}
+
+// observe releases the assumed pod once the informer sees its real pre-allocation.
+func (c *assumeCache) observe(pod *v1.Pod) {
+	c.mu.RLock()
+	assumed, ok := c.pods[pod.UID]
+	c.mu.RUnlock()
+	if !ok {
+		return
+	}
+	preAlloc, _ := util.HasAnnotation(pod, util.PodVGPUPreAllocAnnotation)
+	if preAlloc == assumed.Annotations[util.PodVGPUPreAllocAnnotation] || util.PodIsTerminated(pod) {
+		klog.V(5).InfoS("Informer observed the assumed pod", "pod", klog.KObj(pod))
+		c.Forget(pod.UID)
+	}
+}
@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"math"
 	"testing"
+	"time"
 
 	"github.com/coldzerofear/vgpu-manager/pkg/util"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -18,6 +20,7 @@ import (
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	testing2 "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/events"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
@@ -31,14 +34,23 @@ var _ = Describe("VGPUSchedulerPlugin Bind", func() {
 		plugin    *VGPUSchedulerPlugin
 		ctx       context.Context
 		fakeCli   *fake.Clientset
+		recorder  *events.FakeRecorder
 		testPod   *v1.Pod
 		testState *framework.CycleState
 		nodeName  = "test-node"
+		preAlloc  = "default[0_GPU-xxxx_0_2048]"
 	)
 
+	getPod := func() *v1.Pod {
+		updatedPod, err := fakeCli.CoreV1().Pods(testPod.Namespace).Get(ctx, testPod.Name, metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		return updatedPod
+	}
+
 	BeforeEach(func() {
 		ctx = context.Background()
 		fakeCli = fake.NewSimpleClientset()
+		recorder = events.NewFakeRecorder(10)
 		testPod = &v1.Pod{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      "test-pod",
@@ -48,87 +60,78 @@ var _ = Describe("VGPUSchedulerPlugin Bind", func() {
 			Spec: v1.PodSpec{
 				Containers: []v1.Container{{
 					Name: "default",
+					Resources: v1.ResourceRequirements{
+						Limits: v1.ResourceList{
+							v1.ResourceCPU:              resource.MustParse("1"),
+							v1.ResourceMemory:           resource.MustParse("1Gi"),
+							util.VGPUNumberResourceName: resource.MustParse("1"),
+						},
+					},
 				}},
 			},
 		}
-		testState = framework.NewCycleState()
 		plugin = &VGPUSchedulerPlugin{
 			handle: &frameworkHandleStub{
 				clientSet: fakeCli,
+				recorder:  recorder,
 			},
+			throttle:    newBindThrottle(0, nil),
+			cache:       newDeviceCache(),
+			bindEnabled: true,
 		}
+		testState = framework.NewCycleState()
+		testState.Write(plugin.preAllocateDeviceKey(nodeName), preAllocateDevice(preAlloc))
+	})
+
+	JustBeforeEach(func() {
+		_, err := fakeCli.CoreV1().Pods(testPod.Namespace).Create(ctx, testPod, metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
 	})
 
-	Context("when binding non-vGPU pod", func() {
+	Context("when pre-binding non-vGPU pod", func() {
 		BeforeEach(func() {
-			testPod.Spec.Containers[0].Resources = v1.ResourceRequirements{
-				Limits: v1.ResourceList{
-					v1.ResourceCPU:    resource.MustParse("1"),
-					v1.ResourceMemory: resource.MustParse("1Gi"),
-				},
-			}
-			_, _ = fakeCli.CoreV1().Pods(testPod.Namespace).Create(ctx, testPod, metav1.CreateOptions{})
+			delete(testPod.Spec.Containers[0].Resources.Limits, util.VGPUNumberResourceName)
 		})
 		It("should succeed with correct labels and annotations", func() {
-
-			status := plugin.Bind(ctx, testState, testPod, nodeName)
+			status := plugin.PreBind(ctx, testState, testPod, nodeName)
 			Expect(status.IsSuccess()).To(BeTrue())
-			updatedPod, err := fakeCli.CoreV1().Pods(testPod.Namespace).Get(ctx, testPod.Name, metav1.GetOptions{})
-			Expect(err).NotTo(HaveOccurred())
-			//Expect(updatedPod.Spec.NodeName).To(Equal(nodeName))
+			updatedPod := getPod()
 			Expect(updatedPod.Labels[util.PodAssignedPhaseLabel]).To(Equal(string(util.AssignPhaseSucceed)))
 			Expect(updatedPod.Annotations[util.PodPredicateTimeAnnotation]).To(Equal(fmt.Sprintf("%d", uint64(math.MaxUint64))))
 		})
-		AfterEach(func() {
-			_ = fakeCli.CoreV1().Pods(testPod.Namespace).Delete(ctx, testPod.Name, metav1.DeleteOptions{})
-		})
 	})
 
-	Context("when binding vGPU pod", func() {
-		preAllocStr := "default[0_GPU-xxxx_0_2048]"
-		BeforeEach(func() {
-			preAlloc := preAllocateDevice(preAllocStr)
-			testState.Write(plugin.preAllocateDeviceKey(nodeName), preAlloc)
-			testPod.Spec.Containers[0].Resources = v1.ResourceRequirements{
-				Limits: v1.ResourceList{
-					v1.ResourceCPU:              resource.MustParse("1"),
-					v1.ResourceMemory:           resource.MustParse("1Gi"),
-					util.VGPUNumberResourceName: resource.MustParse("1"),
-				},
-			}
-			_, _ = fakeCli.CoreV1().Pods(testPod.Namespace).Create(ctx, testPod, metav1.CreateOptions{})
-		})
+	Context("when pre-binding vGPU pod", func() {
 		It("should succeed with vGPU annotations", func() {
-			status := plugin.Bind(ctx, testState, testPod, nodeName)
+			status := plugin.PreBind(ctx, testState, testPod, nodeName)
 			Expect(status.IsSuccess()).To(BeTrue())
-
-			updatedPod, err := fakeCli.CoreV1().Pods(testPod.Namespace).Get(ctx, testPod.Name, metav1.GetOptions{})
-			Expect(err).NotTo(HaveOccurred())
-			//Expect(updatedPod.Spec.NodeName).To(Equal(nodeName))
+			updatedPod := getPod()
+			Expect(updatedPod.Spec.NodeName).To(BeEmpty())
 			Expect(updatedPod.Labels[util.PodAssignedPhaseLabel]).To(Equal(string(util.AssignPhaseAllocating)))
-			Expect(updatedPod.Annotations[util.PodVGPUPreAllocAnnotation]).To(Equal(preAllocStr))
+			Expect(updatedPod.Annotations[util.PodVGPUPreAllocAnnotation]).To(Equal(preAlloc))
 			Expect(updatedPod.Annotations[util.PodPredicateNodeAnnotation]).To(Equal(nodeName))
 			Expect(updatedPod.Annotations[util.PodVGPURealAllocAnnotation]).To(Equal(""))
 		})
-		AfterEach(func() {
-			testState.Delete(plugin.preAllocateDeviceKey(nodeName))
-			_ = fakeCli.CoreV1().Pods(testPod.Namespace).Delete(ctx, testPod.Name, metav1.DeleteOptions{})
+		It("should hold the node until the binding finished", func() {
+			Expect(plugin.PreBind(ctx, testState, testPod, nodeName).IsSuccess()).To(BeTrue())
+			timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+			defer cancel()
+			_, err := plugin.throttle.Acquire(timeoutCtx, nodeName, &v1.Pod{})
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+
+			plugin.PostBind(ctx, testState, testPod, nodeName)
+			release, err := plugin.throttle.Acquire(ctx, nodeName, &v1.Pod{})
+			Expect(err).NotTo(HaveOccurred())
+			release(false)
 		})
 	})
 
 	Context("when state read fails", func() {
 		BeforeEach(func() {
-			testPod.Spec.Containers[0].Resources = v1.ResourceRequirements{
-				Limits: v1.ResourceList{
-					v1.ResourceCPU:              resource.MustParse("1"),
-					v1.ResourceMemory:           resource.MustParse("1Gi"),
-					util.VGPUNumberResourceName: resource.MustParse("1"),
-				},
-			}
 			testState.Delete(plugin.preAllocateDeviceKey(nodeName))
 		})
 		It("should return error status", func() {
-			status := plugin.Bind(ctx, testState, testPod, nodeName)
+			status := plugin.PreBind(ctx, testState, testPod, nodeName)
 			Expect(status.Code()).To(Equal(framework.Error))
 			Expect(status.Message()).To(ContainSubstring("getting pre allocated devices for node failed"))
 		})
@@ -137,58 +140,86 @@ var _ = Describe("VGPUSchedulerPlugin Bind", func() {
 	Context("when patch metadata fails", func() {
 		patchErr := errors.New("patch error")
 		BeforeEach(func() {
-			preAllocStr := "default[0_GPU-xxxx_0_2048]"
-			preAlloc := preAllocateDevice(preAllocStr)
-			testState.Write(plugin.preAllocateDeviceKey(nodeName), preAlloc)
 			fakeCli.PrependReactor("patch", "pods", func(action testing2.Action) (handled bool, ret runtime.Object, err error) {
 				return true, nil, patchErr
 			})
 		})
-
-		It("should return error status", func() {
-			status := plugin.Bind(ctx, testState, testPod, nodeName)
+		It("should return error status and release the node", func() {
+			status := plugin.PreBind(ctx, testState, testPod, nodeName)
 			Expect(status.Code()).To(Equal(framework.Error))
 			Expect(status.Message()).To(ContainSubstring(patchErr.Error()))
+
+			plugin.Unreserve(ctx, testState, testPod, nodeName)
+			Expect(recorder.Events).To(BeEmpty())
+			release, err := plugin.throttle.Acquire(ctx, nodeName, &v1.Pod{})
+			Expect(err).NotTo(HaveOccurred())
+			release(false)
 		})
+	})
 
-		AfterEach(func() {
-			testState.Delete(plugin.preAllocateDeviceKey(nodeName))
+	Context("when binding", func() {
+		It("should bind the pod when enabled", func() {
+			Expect(plugin.Bind(ctx, testState, testPod, nodeName).IsSuccess()).To(BeTrue())
+		})
+		It("should leave the pod to the next bind plugin when disabled", func() {
+			plugin.bindEnabled = false
+			Expect(plugin.Bind(ctx, testState, testPod, nodeName).IsSkip()).To(BeTrue())
 		})
 	})
 
 	Context("when bind operation fails", func() {
-		preAllocStr := "default[0_GPU-xxxx_0_2048]"
 		BeforeEach(func() {
-			preAlloc := preAllocateDevice(preAllocStr)
-			testState.Write(plugin.preAllocateDeviceKey(nodeName), preAlloc)
 			fakeCli.PrependReactor("create", "pods", func(action testing2.Action) (bool, runtime.Object, error) {
 				if action.GetSubresource() == "binding" {
 					return true, nil, errors.New("bind failed")
 				}
 				return false, nil, nil
 			})
-			testPod.Spec.Containers[0].Resources = v1.ResourceRequirements{
-				Limits: v1.ResourceList{
-					v1.ResourceCPU:              resource.MustParse("1"),
-					v1.ResourceMemory:           resource.MustParse("1Gi"),
-					util.VGPUNumberResourceName: resource.MustParse("1"),
-				},
-			}
-			_, _ = fakeCli.CoreV1().Pods(testPod.Namespace).Create(ctx, testPod, metav1.CreateOptions{})
+			testPod.Labels = map[string]string{"app": "test"}
+			testPod.Annotations = map[string]string{util.PodPredicateTimeAnnotation: "1"}
 		})
 
-		It("should return error status and mark allocation failed", func() {
+		It("should revert the vGPU metadata in Unreserve", func() {
+			Expect(plugin.PreBind(ctx, testState, testPod, nodeName).IsSuccess()).To(BeTrue())
 			status := plugin.Bind(ctx, testState, testPod, nodeName)
 			Expect(status.Code()).To(Equal(framework.Error))
-			updatedPod, err := fakeCli.CoreV1().Pods(testPod.Namespace).Get(ctx, testPod.Name, metav1.GetOptions{})
-			Expect(err).NotTo(HaveOccurred())
+			plugin.Unreserve(ctx, testState, testPod, nodeName)
+
+			updatedPod := getPod()
 			Expect(updatedPod.Spec.NodeName).To(BeEmpty())
-			Expect(updatedPod.Labels[util.PodAssignedPhaseLabel]).To(Equal(string(util.AssignPhaseFailed)))
-			Expect(updatedPod.Annotations[util.PodPredicateTimeAnnotation]).To(Equal(fmt.Sprintf("%d", uint64(math.MaxUint64))))
+			Expect(updatedPod.Labels).To(Equal(map[string]string{"app": "test"}))
+			Expect(updatedPod.Annotations).To(Equal(map[string]string{util.PodPredicateTimeAnnotation: "1"}))
+			Expect(<-recorder.Events).To(ContainSubstring("VGPUBindRolledBack"))
 		})
-		AfterEach(func() {
-			testState.Delete(plugin.preAllocateDeviceKey(nodeName))
-			_ = fakeCli.CoreV1().Pods(testPod.Namespace).Delete(ctx, testPod.Name, metav1.DeleteOptions{})
+		It("should not modify the pod held by the scheduler", func() {
+			testPod.Spec.NodeName = nodeName
+			assumedPod := testPod.DeepCopy()
+			Expect(plugin.PreBind(ctx, testState, testPod, nodeName).IsSuccess()).To(BeTrue())
+			Expect(plugin.Bind(ctx, testState, testPod, nodeName).Code()).To(Equal(framework.Error))
+			plugin.Unreserve(ctx, testState, testPod, nodeName)
+			Expect(testPod).To(Equal(assumedPod))
+		})
+		It("should not revert the vGPU metadata of a bound pod", func() {
+			Expect(plugin.PreBind(ctx, testState, testPod, nodeName).IsSuccess()).To(BeTrue())
+			Expect(plugin.Bind(ctx, testState, testPod, nodeName).Code()).To(Equal(framework.Error))
+			boundPod := getPod()
+			boundPod.Spec.NodeName = nodeName
+			_, err := fakeCli.CoreV1().Pods(testPod.Namespace).Update(ctx, boundPod, metav1.UpdateOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			plugin.Unreserve(ctx, testState, testPod, nodeName)
+			Expect(getPod().Labels[util.PodAssignedPhaseLabel]).To(Equal(string(util.AssignPhaseAllocating)))
+		})
+		It("should report an event when the vGPU metadata cannot be reverted", func() {
+			Expect(plugin.PreBind(ctx, testState, testPod, nodeName).IsSuccess()).To(BeTrue())
+			reverts := 0
+			fakeCli.PrependReactor("patch", "pods", func(action testing2.Action) (bool, runtime.Object, error) {
+				reverts++
+				return true, nil, apierrors.NewConflict(v1.Resource("pods"), testPod.Name, errors.New("revert failed"))
+			})
+			Expect(plugin.Bind(ctx, testState, testPod, nodeName).Code()).To(Equal(framework.Error))
+			plugin.Unreserve(ctx, testState, testPod, nodeName)
+			Expect(reverts).To(BeNumerically(">", 1))
+			Expect(<-recorder.Events).To(ContainSubstring("VGPUBindRollbackFailed"))
 		})
 	})
 })
@@ -196,6 +227,11 @@ var _ = Describe("VGPUSchedulerPlugin Bind", func() {
 type frameworkHandleStub struct {
 	framework.Handle
 	clientSet *fake.Clientset
+	recorder  *events.FakeRecorder
+}
+
+func (h *frameworkHandleStub) EventRecorder() events.EventRecorder {
+	return h.recorder
 }
 
 func (h *frameworkHandleStub) ClientSet() clientset.Interface {
@@ -0,0 +1,70 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/client"
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// podMetadataChange records the previous values of the labels and annotations changed
+// by a patch of the pod metadata, nil when the key was not set, so that it can be reverted.
+type podMetadataChange struct {
+	Labels      map[string]*string `json:"labels,omitempty"`
+	Annotations map[string]*string `json:"annotations,omitempty"`
+}
+
+// newPodMetadataChange records the values the patch is going to overwrite, it must be
+// called before the patch is applied.
+func newPodMetadataChange(pod *v1.Pod, patchData client.PatchMetadata) *podMetadataChange {
+	previous := func(current, patched map[string]string) map[string]*string {
+		values := make(map[string]*string, len(patched))
+		for key := range patched {
+			if value, ok := current[key]; ok {
+				values[key] = &value
+			} else {
+				values[key] = nil
+			}
+		}
+		return values
+	}
+	return &podMetadataChange{
+		Labels:      previous(pod.Labels, patchData.Labels),
+		Annotations: previous(pod.Annotations, patchData.Annotations),
+	}
+}
+
+// revert restores the previous values of the changed labels and annotations, removing the
+// keys that were not set. The given pod is not modified, it may be held by the scheduler cache.
+// A pod that no longer exists or was bound after all is left as it is.
+func (c *podMetadataChange) revert(ctx context.Context, kubeClient kubernetes.Interface, pod *v1.Pod) error {
+	patch, err := json.Marshal(map[string]*podMetadataChange{"metadata": c})
+	if err != nil {
+		return err
+	}
+	return retry.OnError(retry.DefaultBackoff, util.ShouldRetry, func() error {
+		current, err := kubeClient.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if current.UID != pod.UID || len(current.Spec.NodeName) > 0 {
+			return nil
+		}
+		_, err = kubeClient.CoreV1().Pods(pod.Namespace).
+			Patch(ctx, pod.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	})
+}
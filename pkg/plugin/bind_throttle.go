@@ -0,0 +1,210 @@
+package plugin
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// bindThrottle serialises the binding of vGPU pods per node. The device plugin of a node
+// allocates devices to pods in allocating phase one by one, so a pod is bound only after
+// the device plugin has picked up the previous pod bound to the same node, or after the
+// throttle interval has elapsed.
+type bindThrottle struct {
+	mu        sync.Mutex
+	interval  time.Duration
+	podLister listerv1.PodLister
+	nodes     map[string]*nodeBindSlot
+}
+
+// nodeBindSlot guards the binding on a node and tracks the last pod bound to it.
+type nodeBindSlot struct {
+	// lock is a semaphore held during the binding of a pod.
+	lock chan struct{}
+	// users is the number of bindings holding or waiting for the lock.
+	users int
+	// deleted is set when the node is deleted while the slot is in use, the last user drops the slot.
+	deleted bool
+	// pod is the pod being bound, or the last pod bound to the node still waiting for the device plugin.
+	pod types.UID
+	// allocated is closed once the device plugin has picked up the pod.
+	allocated chan struct{}
+	// deadline is the time after which the node no longer waits for the pod.
+	deadline time.Time
+}
+
+func newBindThrottle(interval time.Duration, podLister listerv1.PodLister) *bindThrottle {
+	return &bindThrottle{
+		interval:  interval,
+		podLister: podLister,
+		nodes:     make(map[string]*nodeBindSlot),
+	}
+}
+
+func (t *bindThrottle) useSlot(nodeName string) *nodeBindSlot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	slot, ok := t.nodes[nodeName]
+	if !ok {
+		slot = &nodeBindSlot{lock: make(chan struct{}, 1)}
+		t.nodes[nodeName] = slot
+	}
+	slot.users++
+	return slot
+}
+
+func (t *bindThrottle) leaveSlotLocked(nodeName string, slot *nodeBindSlot) {
+	slot.users--
+	if slot.deleted && slot.users == 0 && t.nodes[nodeName] == slot {
+		delete(t.nodes, nodeName)
+	}
+}
+
+// Acquire waits until a pod may be bound to the node. The returned release function
+// must be called once the binding finished, with whether the pod was bound.
+func (t *bindThrottle) Acquire(ctx context.Context, nodeName string, pod *v1.Pod) (release func(bound bool), err error) {
+	slot := t.useSlot(nodeName)
+	abort := func() {
+		t.mu.Lock()
+		t.leaveSlotLocked(nodeName, slot)
+		t.mu.Unlock()
+	}
+	select {
+	case slot.lock <- struct{}{}:
+	case <-ctx.Done():
+		abort()
+		return nil, ctx.Err()
+	}
+	t.mu.Lock()
+	allocated, deadline := slot.allocated, slot.deadline
+	t.mu.Unlock()
+	if allocated != nil {
+		timer := time.NewTimer(time.Until(deadline))
+		select {
+		case <-allocated:
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			abort()
+			<-slot.lock
+			return nil, ctx.Err()
+		}
+		timer.Stop()
+	}
+	// The device plugin may pick up the pod before the binding finished.
+	t.mu.Lock()
+	slot.pod, slot.allocated, slot.deadline = pod.UID, make(chan struct{}), time.Time{}
+	t.mu.Unlock()
+	return func(bound bool) {
+		pickedUp := !bound || t.pickedUp(pod)
+		t.mu.Lock()
+		if slot.pod == pod.UID && slot.allocated != nil {
+			if pickedUp {
+				close(slot.allocated)
+				slot.pod, slot.allocated = "", nil
+			} else {
+				slot.deadline = time.Now().Add(t.interval)
+			}
+		}
+		t.leaveSlotLocked(nodeName, slot)
+		t.mu.Unlock()
+		<-slot.lock
+	}, nil
+}
+
+// pickedUp checks the pod lister for the device plugin having picked up the pod, in case
+// its event was observed before the binding finished.
+func (t *bindThrottle) pickedUp(pod *v1.Pod) bool {
+	if t.podLister == nil {
+		return false
+	}
+	current, err := t.podLister.Pods(pod.Namespace).Get(pod.Name)
+	if apierrors.IsNotFound(err) {
+		return true
+	}
+	return err == nil && (current.UID != pod.UID || allocationFinished(current))
+}
+
+// allocationFinished returns whether the device plugin has picked up the pod.
+func allocationFinished(pod *v1.Pod) bool {
+	phase := pod.Labels[util.PodAssignedPhaseLabel]
+	return util.PodIsTerminated(pod) || phase == string(util.AssignPhaseSucceed) || phase == string(util.AssignPhaseFailed)
+}
+
+// observe releases the node waiting for the pod once the device plugin has picked it up.
+func (t *bindThrottle) observe(pod *v1.Pod, deleted bool) {
+	if !deleted && !allocationFinished(pod) {
+		return
+	}
+	nodeName := pod.Spec.NodeName
+	if len(nodeName) == 0 {
+		nodeName = pod.Annotations[util.PodPredicateNodeAnnotation]
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	slot, ok := t.nodes[nodeName]
+	if !ok || slot.allocated == nil || slot.pod != pod.UID {
+		return
+	}
+	close(slot.allocated)
+	slot.pod, slot.allocated = "", nil
+}
+
+// deleteNode drops the slot of a deleted node, once the bindings using it finished.
+func (t *bindThrottle) deleteNode(nodeName string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	slot, ok := t.nodes[nodeName]
+	if !ok {
+		return
+	}
+	if slot.users > 0 {
+		slot.deleted = true
+		return
+	}
+	delete(t.nodes, nodeName)
+}
+
+// podEventHandler returns the pod event handler that observes the allocation phase of bound pods.
+func (t *bindThrottle) podEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(_, newObj interface{}) {
+			if pod, ok := newObj.(*v1.Pod); ok {
+				t.observe(pod, false)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			switch o := obj.(type) {
+			case *v1.Pod:
+				t.observe(o, true)
+			case cache.DeletedFinalStateUnknown:
+				if pod, ok := o.Obj.(*v1.Pod); ok {
+					t.observe(pod, true)
+				}
+			}
+		},
+	}
+}
+
+// nodeEventHandler returns the node event handler that drops the slots of deleted nodes.
+func (t *bindThrottle) nodeEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		DeleteFunc: func(obj interface{}) {
+			switch o := obj.(type) {
+			case *v1.Node:
+				t.deleteNode(o.Name)
+			case cache.DeletedFinalStateUnknown:
+				if node, ok := o.Obj.(*v1.Node); ok {
+					t.deleteNode(node.Name)
+				}
+			}
+		},
+	}
+}
@@ -0,0 +1,125 @@
+package plugin
+
+import (
+	"context"
+	"time"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+var _ = Describe("VGPUSchedulerPlugin bindThrottle", func() {
+	var (
+		ctx      context.Context
+		indexer  cache.Indexer
+		throttle *bindThrottle
+		boundPod *v1.Pod
+		nodeName = "test-node"
+	)
+
+	newPod := func(name string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				UID:       uuid.NewUUID(),
+				Labels: map[string]string{
+					util.PodAssignedPhaseLabel: string(util.AssignPhaseAllocating),
+				},
+			},
+			Spec: v1.PodSpec{NodeName: nodeName},
+		}
+	}
+	pickedUp := func(pod *v1.Pod) *v1.Pod {
+		allocatedPod := pod.DeepCopy()
+		allocatedPod.Labels[util.PodAssignedPhaseLabel] = string(util.AssignPhaseSucceed)
+		return allocatedPod
+	}
+	bind := func(pod *v1.Pod) {
+		release, err := throttle.Acquire(ctx, nodeName, pod)
+		Expect(err).NotTo(HaveOccurred())
+		release(true)
+	}
+	expectWaiting := func(nodeName string) {
+		timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err := throttle.Acquire(timeoutCtx, nodeName, newPod("next-pod"))
+		ExpectWithOffset(1, err).To(MatchError(context.DeadlineExceeded))
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		indexer = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		throttle = newBindThrottle(time.Hour, listerv1.NewPodLister(indexer))
+		boundPod = newPod("bound-pod")
+		Expect(indexer.Add(boundPod)).To(Succeed())
+		bind(boundPod)
+	})
+
+	It("should not wait on other nodes", func() {
+		release, err := throttle.Acquire(ctx, "other-node", newPod("other-pod"))
+		Expect(err).NotTo(HaveOccurred())
+		release(true)
+	})
+
+	It("should wait until the device plugin picked up the previous pod", func() {
+		expectWaiting(nodeName)
+		throttle.observe(boundPod, false)
+		throttle.observe(pickedUp(boundPod), false)
+		release, err := throttle.Acquire(ctx, nodeName, newPod("next-pod"))
+		Expect(err).NotTo(HaveOccurred())
+		release(false)
+	})
+
+	It("should stop waiting when the previous pod is deleted", func() {
+		throttle.observe(boundPod, true)
+		release, err := throttle.Acquire(ctx, nodeName, newPod("next-pod"))
+		Expect(err).NotTo(HaveOccurred())
+		release(false)
+	})
+
+	It("should stop waiting after the throttle interval", func() {
+		throttle = newBindThrottle(10*time.Millisecond, nil)
+		bind(boundPod)
+		release, err := throttle.Acquire(ctx, nodeName, newPod("next-pod"))
+		Expect(err).NotTo(HaveOccurred())
+		release(false)
+	})
+
+	It("should not wait for a pod picked up before its binding finished", func() {
+		throttle.observe(pickedUp(boundPod), false)
+		fastPod := newPod("fast-pod")
+		Expect(indexer.Add(fastPod)).To(Succeed())
+		release, err := throttle.Acquire(ctx, nodeName, fastPod)
+		Expect(err).NotTo(HaveOccurred())
+		throttle.observe(pickedUp(fastPod), false)
+		release(true)
+		bind(newPod("next-pod"))
+	})
+
+	It("should check the pod lister once the binding finished", func() {
+		throttle.observe(pickedUp(boundPod), false)
+		fastPod := newPod("fast-pod")
+		Expect(indexer.Add(pickedUp(fastPod))).To(Succeed())
+		bind(fastPod)
+		bind(newPod("next-pod"))
+	})
+
+	It("should keep the slot of a deleted node until its binding finished", func() {
+		throttle.observe(pickedUp(boundPod), false)
+		release, err := throttle.Acquire(ctx, nodeName, newPod("binding-pod"))
+		Expect(err).NotTo(HaveOccurred())
+		throttle.deleteNode(nodeName)
+		expectWaiting(nodeName)
+
+		release(false)
+		Expect(throttle.nodes).NotTo(HaveKey(nodeName))
+		bind(newPod("next-pod"))
+	})
+})
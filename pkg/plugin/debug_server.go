@@ -0,0 +1,165 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/device"
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const debugNodesPath = "/debug/vgpu/nodes"
+
+type debugNode struct {
+	Name  string     `json:"name"`
+	GPUs  []debugGPU `json:"gpus,omitempty"`
+	Error string     `json:"error,omitempty"`
+}
+
+type debugGPU struct {
+	ID          int        `json:"id"`
+	UUID        string     `json:"uuid"`
+	Type        string     `json:"type"`
+	Healthy     bool       `json:"healthy"`
+	Quarantined bool       `json:"quarantined,omitempty"`
+	TotalNumber int        `json:"totalNumber"`
+	UsedNumber  int        `json:"usedNumber"`
+	TotalCores  int        `json:"totalCores"`
+	UsedCores   int        `json:"usedCores"`
+	TotalMemory int        `json:"totalMemory"`
+	UsedMemory  int        `json:"usedMemory"`
+	Pods        []debugPod `json:"pods,omitempty"`
+}
+
+type debugPod struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	UID       string `json:"uid"`
+	Container string `json:"container"`
+	Cores     int    `json:"cores"`
+	Memory    int    `json:"memory"`
+	// Assumed is whether the devices are only pre-allocated by the scheduler and not yet bound.
+	Assumed bool `json:"assumed"`
+	// Phase is the device assignment phase of the pod, allocating while the device plugin
+	// has not picked up the pre-allocation yet.
+	Phase string `json:"phase,omitempty"`
+}
+
+// debugNodeView returns the device state of the node as seen by the plugin.
+func (c *deviceCache) debugNodeView(node *v1.Node) debugNode {
+	view := debugNode{Name: node.Name}
+	info, err := c.Snapshot(node)
+	if err != nil {
+		view.Error = err.Error()
+		return view
+	}
+	gpuPods := map[int][]debugPod{}
+	for _, pod := range c.PodsOnNode(node.Name) {
+		assumed := c.assumed.IsAssumed(pod.UID)
+		phase, _ := util.HasLabel(pod, util.PodAssignedPhaseLabel)
+		for _, container := range device.GetPodAssignDevices(pod) {
+			for _, dev := range container.Devices {
+				gpuPods[dev.Id] = append(gpuPods[dev.Id], debugPod{
+					Namespace: pod.Namespace,
+					Name:      pod.Name,
+					UID:       string(pod.UID),
+					Container: container.Name,
+					Cores:     dev.Cores,
+					Memory:    dev.Memory,
+					Assumed:   assumed,
+					Phase:     phase,
+				})
+			}
+		}
+	}
+	quarantined := quarantinedGPUs(node)
+	for _, dev := range info.GetDeviceList() {
+		gpu := info.GetDeviceMap()[dev.Index]
+		if gpu == nil {
+			continue
+		}
+		view.GPUs = append(view.GPUs, debugGPU{
+			ID:          gpu.GetID(),
+			UUID:        gpu.GetUUID(),
+			Type:        gpu.GetType(),
+			Healthy:     gpu.Healthy(),
+			Quarantined: isListed(quarantined, gpu),
+			TotalNumber: gpu.GetTotalNumber(),
+			UsedNumber:  gpu.GetTotalNumber() - gpu.AllocatableNumber(),
+			TotalCores:  gpu.GetTotalCores(),
+			UsedCores:   gpu.GetTotalCores() - gpu.AllocatableCores(),
+			TotalMemory: gpu.GetTotalMemory(),
+			UsedMemory:  gpu.GetTotalMemory() - gpu.AllocatableMemory(),
+			Pods:        gpuPods[gpu.GetID()],
+		})
+	}
+	return view
+}
+
+// debugHandler serves the device state of all nodes on debugNodesPath,
+// and of a single node on debugNodesPath/<node>.
+func (c *deviceCache) debugHandler() http.Handler {
+	mux := http.NewServeMux()
+	serve := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		nodeName := strings.Trim(strings.TrimPrefix(r.URL.Path, debugNodesPath), "/")
+		var result interface{}
+		if len(nodeName) == 0 {
+			views := []debugNode{}
+			for _, node := range c.Nodes() {
+				views = append(views, c.debugNodeView(node))
+			}
+			result = views
+		} else {
+			var found *v1.Node
+			for _, node := range c.Nodes() {
+				if node.Name == nodeName {
+					found = node
+					break
+				}
+			}
+			if found == nil {
+				http.Error(w, "node not found", http.StatusNotFound)
+				return
+			}
+			result = c.debugNodeView(found)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			klog.ErrorS(err, "writing vGPU debug response failed")
+		}
+	}
+	mux.HandleFunc(debugNodesPath, serve)
+	mux.HandleFunc(debugNodesPath+"/", serve)
+	return mux
+}
+
+// startDebugServer serves the debug handler on the address until the context is done.
+func startDebugServer(ctx context.Context, address string, handler http.Handler) {
+	server := &http.Server{
+		Addr:              address,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	go func() {
+		klog.InfoS("Starting vGPU debug server", "address", address)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			klog.ErrorS(err, "vGPU debug server failed", "address", address)
+		}
+	}()
+}
@@ -0,0 +1,106 @@
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+var _ = Describe("VGPUSchedulerPlugin debug handler", func() {
+	var (
+		devCache *deviceCache
+		handler  http.Handler
+		testNode *v1.Node
+	)
+
+	newPod := func(name string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: uuid.NewUUID()},
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{{
+					Name: "default",
+					Resources: v1.ResourceRequirements{
+						Limits: v1.ResourceList{util.VGPUNumberResourceName: resource.MustParse("1")},
+					},
+				}},
+			},
+		}
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	BeforeEach(func() {
+		devCache = newDeviceCache()
+		handler = devCache.debugHandler()
+		testNode = &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-node",
+				UID:  uuid.NewUUID(),
+				Annotations: map[string]string{
+					util.NodeDeviceRegisterAnnotation: `[{"id":0,"uuid":"GPU-0","core":100,"memory":10240,"number":10,"healthy":true},` +
+						`{"id":1,"uuid":"GPU-1","core":100,"memory":10240,"number":10,"healthy":true}]`,
+				},
+			},
+		}
+		devCache.updateNode(testNode)
+	})
+
+	It("should dump the devices and pods of each node", func() {
+		boundPod := newPod("bound-pod")
+		boundPod.Spec.NodeName = testNode.Name
+		boundPod.Labels = map[string]string{util.PodAssignedPhaseLabel: string(util.AssignPhaseSucceed)}
+		boundPod.Annotations = map[string]string{util.PodVGPURealAllocAnnotation: "default[0_GPU-0_50_2048]"}
+		devCache.updatePod(boundPod)
+		assumedPod := newPod("assumed-pod")
+		devCache.assumed.Assume(assumedPod, testNode.Name, "default[1_GPU-1_100_10240]")
+
+		recorder := get(debugNodesPath)
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		var nodes []debugNode
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &nodes)).To(Succeed())
+		Expect(nodes).To(HaveLen(1))
+		Expect(nodes[0].Name).To(Equal(testNode.Name))
+		Expect(nodes[0].GPUs).To(HaveLen(2))
+
+		gpu0 := nodes[0].GPUs[0]
+		Expect(gpu0.UUID).To(Equal("GPU-0"))
+		Expect(gpu0.UsedCores).To(Equal(50))
+		Expect(gpu0.UsedMemory).To(Equal(2048))
+		Expect(gpu0.TotalMemory).To(Equal(10240))
+		Expect(gpu0.Pods).To(Equal([]debugPod{{
+			Namespace: "default", Name: "bound-pod", UID: string(boundPod.UID), Container: "default",
+			Cores: 50, Memory: 2048, Phase: string(util.AssignPhaseSucceed),
+		}}))
+
+		gpu1 := nodes[0].GPUs[1]
+		Expect(gpu1.UUID).To(Equal("GPU-1"))
+		Expect(gpu1.UsedCores).To(Equal(100))
+		Expect(gpu1.Pods).To(HaveLen(1))
+		Expect(gpu1.Pods[0].Name).To(Equal("assumed-pod"))
+		Expect(gpu1.Pods[0].Assumed).To(BeTrue())
+	})
+
+	It("should dump a single node and reject unknown nodes", func() {
+		recorder := get(debugNodesPath + "/" + testNode.Name)
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		var node debugNode
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &node)).To(Succeed())
+		Expect(node.Name).To(Equal(testNode.Name))
+		Expect(node.GPUs).To(HaveLen(2))
+		Expect(node.GPUs[0].Pods).To(BeEmpty())
+
+		Expect(get(debugNodesPath + "/unknown-node").Code).To(Equal(http.StatusNotFound))
+	})
+})
@@ -0,0 +1,263 @@
+package plugin
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/device"
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// deviceCache maintains the device allocation state of each node incrementally
+// from pod and node events, instead of listing all pods for every node evaluated.
+type deviceCache struct {
+	mu    sync.Mutex
+	nodes map[string]*nodeDeviceEntry
+	// podNodes records the node on which each vGPU pod is accounted.
+	podNodes map[types.UID]string
+	assumed  *assumeCache
+}
+
+type nodeDeviceEntry struct {
+	node *v1.Node
+	pods map[types.UID]*v1.Pod
+	// info is the device state built from node and pods, nil means it needs to be rebuilt.
+	info *device.NodeInfo
+}
+
+func newDeviceCache() *deviceCache {
+	return &deviceCache{
+		nodes:    make(map[string]*nodeDeviceEntry),
+		podNodes: make(map[types.UID]string),
+		assumed:  newAssumeCache(),
+	}
+}
+
+func (c *deviceCache) getOrCreateEntry(nodeName string) *nodeDeviceEntry {
+	entry, ok := c.nodes[nodeName]
+	if !ok {
+		entry = &nodeDeviceEntry{pods: make(map[types.UID]*v1.Pod)}
+		c.nodes[nodeName] = entry
+	}
+	return entry
+}
+
+// allocationFailed returns whether the device allocation of the pod failed, e.g. timed out.
+func allocationFailed(pod *v1.Pod) bool {
+	return pod.Labels[util.PodAssignedPhaseLabel] == string(util.AssignPhaseFailed)
+}
+
+// accountedNodeName returns the node on which the pod occupies devices, or empty if it occupies none.
+func accountedNodeName(pod *v1.Pod) string {
+	if !util.IsVGPUResourcePod(pod) || pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed ||
+		allocationFailed(pod) {
+		return ""
+	}
+	if pod.Spec.NodeName != "" {
+		return pod.Spec.NodeName
+	}
+	nodeName, _ := util.HasAnnotation(pod, util.PodPredicateNodeAnnotation)
+	return nodeName
+}
+
+func (c *deviceCache) updatePod(pod *v1.Pod) {
+	c.mu.Lock()
+	nodeName := accountedNodeName(pod)
+	if oldNodeName, ok := c.podNodes[pod.UID]; ok && oldNodeName != nodeName {
+		c.removePodLocked(pod.UID)
+	}
+	if nodeName != "" {
+		entry := c.getOrCreateEntry(nodeName)
+		entry.pods[pod.UID] = pod
+		entry.info = nil
+		c.podNodes[pod.UID] = nodeName
+	}
+	c.mu.Unlock()
+	c.assumed.observe(pod)
+}
+
+func (c *deviceCache) deletePod(pod *v1.Pod) {
+	c.mu.Lock()
+	c.removePodLocked(pod.UID)
+	c.mu.Unlock()
+	c.assumed.Forget(pod.UID)
+}
+
+func (c *deviceCache) removePodLocked(uid types.UID) {
+	nodeName, ok := c.podNodes[uid]
+	if !ok {
+		return
+	}
+	delete(c.podNodes, uid)
+	if entry, ok := c.nodes[nodeName]; ok {
+		delete(entry.pods, uid)
+		entry.info = nil
+		if entry.node == nil && len(entry.pods) == 0 {
+			delete(c.nodes, nodeName)
+		}
+	}
+}
+
+func (c *deviceCache) updateNode(node *v1.Node) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := c.getOrCreateEntry(node.Name)
+	entry.node = node
+	entry.info = nil
+}
+
+func (c *deviceCache) deleteNode(node *v1.Node) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.nodes[node.Name]
+	if !ok {
+		return
+	}
+	if len(entry.pods) == 0 {
+		delete(c.nodes, node.Name)
+		return
+	}
+	entry.node = nil
+	entry.info = nil
+}
+
+// Snapshot returns a copy of the node device state, including the devices assumed in the Reserve phase.
+func (c *deviceCache) Snapshot(node *v1.Node) (*device.NodeInfo, error) {
+	c.mu.Lock()
+	entry := c.getOrCreateEntry(node.Name)
+	if entry.node == nil {
+		entry.node = node
+	}
+	if entry.info == nil {
+		info, err := device.NewNodeInfo(entry.node, slices.Collect(maps.Values(entry.pods)))
+		if err != nil {
+			c.mu.Unlock()
+			return nil, err
+		}
+		entry.info = info
+	}
+	devNodeInfo := entry.info.Clone().(*device.NodeInfo)
+	assumedPods := c.assumed.PodsOnNode(node.Name)
+	// The informer may still hold a stale allocation of an assumed pod,
+	// in which case the node state has to be rebuilt with the assumed one.
+	var stalePods []*v1.Pod
+	if slices.ContainsFunc(assumedPods, func(pod *v1.Pod) bool {
+		_, ok := entry.pods[pod.UID]
+		return ok
+	}) {
+		stalePods = slices.Collect(maps.Values(entry.pods))
+	}
+	nodeObj := entry.node
+	c.mu.Unlock()
+
+	if stalePods != nil {
+		return device.NewNodeInfo(nodeObj, c.assumed.Merge(stalePods))
+	}
+	for _, pod := range assumedPods {
+		if err := addPodDevices(devNodeInfo, pod); err != nil {
+			return nil, err
+		}
+	}
+	return devNodeInfo, nil
+}
+
+// Nodes returns the nodes known to the cache, sorted by name.
+func (c *deviceCache) Nodes() []*v1.Node {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	nodes := make([]*v1.Node, 0, len(c.nodes))
+	for _, entry := range c.nodes {
+		if entry.node != nil {
+			nodes = append(nodes, entry.node)
+		}
+	}
+	slices.SortFunc(nodes, func(a, b *v1.Node) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return nodes
+}
+
+// PodsOnNode returns the pods occupying devices on the node, including the assumed pods.
+func (c *deviceCache) PodsOnNode(nodeName string) []*v1.Pod {
+	c.mu.Lock()
+	var pods []*v1.Pod
+	if entry, ok := c.nodes[nodeName]; ok {
+		pods = slices.Collect(maps.Values(entry.pods))
+	}
+	c.mu.Unlock()
+	pods = slices.DeleteFunc(c.assumed.Merge(pods), func(pod *v1.Pod) bool {
+		return accountedNodeName(pod) != nodeName
+	})
+	return pods
+}
+
+// addPodDevices records the devices assigned to the pod as used on the node.
+func addPodDevices(info *device.NodeInfo, pod *v1.Pod) error {
+	for _, container := range device.GetPodAssignDevices(pod) {
+		for _, dev := range container.Devices {
+			if err := info.AddUsedResources(dev.Id, dev.Cores, dev.Memory); err != nil {
+				return fmt.Errorf("adding devices of pod %s: %w", klog.KObj(pod), err)
+			}
+		}
+	}
+	return nil
+}
+
+// podEventHandler returns the pod event handler that keeps the cache in sync with the informer.
+func (c *deviceCache) podEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if pod, ok := obj.(*v1.Pod); ok {
+				c.updatePod(pod)
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			if pod, ok := newObj.(*v1.Pod); ok {
+				c.updatePod(pod)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			switch t := obj.(type) {
+			case *v1.Pod:
+				c.deletePod(t)
+			case cache.DeletedFinalStateUnknown:
+				if pod, ok := t.Obj.(*v1.Pod); ok {
+					c.deletePod(pod)
+				}
+			}
+		},
+	}
+}
+
+// nodeEventHandler returns the node event handler that keeps the cache in sync with the informer.
+func (c *deviceCache) nodeEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if node, ok := obj.(*v1.Node); ok {
+				c.updateNode(node)
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			if node, ok := newObj.(*v1.Node); ok {
+				c.updateNode(node)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			switch t := obj.(type) {
+			case *v1.Node:
+				c.deleteNode(t)
+			case cache.DeletedFinalStateUnknown:
+				if node, ok := t.Obj.(*v1.Node); ok {
+					c.deleteNode(node)
+				}
+			}
+		},
+	}
+}
@@ -0,0 +1,102 @@
+package plugin
+
+import (
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+var _ = Describe("VGPUSchedulerPlugin deviceCache", func() {
+	var (
+		devCache *deviceCache
+		testNode *v1.Node
+		testPod  *v1.Pod
+	)
+
+	BeforeEach(func() {
+		devCache = newDeviceCache()
+		testNode = &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-node",
+				UID:  uuid.NewUUID(),
+				Annotations: map[string]string{
+					util.NodeDeviceRegisterAnnotation: `[{"id":0,"uuid":"GPU-0","core":100,"memory":10240,"number":10,"healthy":true},` +
+						`{"id":1,"uuid":"GPU-1","core":100,"memory":10240,"number":10,"healthy":true}]`,
+				},
+			},
+		}
+		testPod = &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-pod",
+				Namespace: "default",
+				UID:       uuid.NewUUID(),
+				Annotations: map[string]string{
+					util.PodVGPURealAllocAnnotation: "default[0_GPU-0_50_2048]",
+				},
+			},
+			Spec: v1.PodSpec{
+				NodeName: testNode.Name,
+				Containers: []v1.Container{{
+					Name: "default",
+					Resources: v1.ResourceRequirements{
+						Limits: v1.ResourceList{
+							util.VGPUNumberResourceName: resource.MustParse("1"),
+						},
+					},
+				}},
+			},
+		}
+		devCache.updateNode(testNode)
+	})
+
+	It("should account the devices of pods added to the node", func() {
+		devCache.updatePod(testPod)
+		info, err := devCache.Snapshot(testNode)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.GetTotalMemory()).To(Equal(20480))
+		Expect(info.GetAvailableMemory()).To(Equal(20480 - 2048))
+		Expect(info.GetAvailableCores()).To(Equal(200 - 50))
+	})
+
+	It("should release the devices of deleted and terminated pods", func() {
+		devCache.updatePod(testPod)
+		devCache.deletePod(testPod)
+		info, err := devCache.Snapshot(testNode)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.GetAvailableMemory()).To(Equal(20480))
+
+		devCache.updatePod(testPod)
+		terminated := testPod.DeepCopy()
+		terminated.Status.Phase = v1.PodSucceeded
+		devCache.updatePod(terminated)
+		info, err = devCache.Snapshot(testNode)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.GetAvailableMemory()).To(Equal(20480))
+	})
+
+	It("should include the assumed devices and replace stale allocations", func() {
+		devCache.assumed.Assume(testPod, testNode.Name, "default[1_GPU-1_100_10240]")
+		info, err := devCache.Snapshot(testNode)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.GetAvailableMemory()).To(Equal(20480 - 10240))
+
+		// The informer version of the pod still carries the previous allocation.
+		devCache.updatePod(testPod)
+		info, err = devCache.Snapshot(testNode)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.GetAvailableMemory()).To(Equal(20480 - 10240))
+	})
+
+	It("should not modify the cached state through a snapshot", func() {
+		info, err := devCache.Snapshot(testNode)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.AddUsedResources(0, 100, 10240)).To(Succeed())
+		info, err = devCache.Snapshot(testNode)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.GetAvailableMemory()).To(Equal(20480))
+	})
+})
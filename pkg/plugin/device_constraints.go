@@ -0,0 +1,131 @@
+package plugin
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/device"
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	// PodGPUMinMemoryAnnotation is the minimum total memory in MiB of each GPU allocated to the pod.
+	PodGPUMinMemoryAnnotation = util.DomainPrefix + "/gpu-min-memory"
+	// PodGPUMinComputeCapabilityAnnotation is the minimum compute capability (e.g. "8.0") of each GPU allocated to the pod.
+	PodGPUMinComputeCapabilityAnnotation = util.DomainPrefix + "/gpu-min-compute-capability"
+	// PodIncludeGPUIndexAnnotation lists the indexes of the GPUs that may be allocated to the pod, e.g. "0,2".
+	PodIncludeGPUIndexAnnotation = util.DomainPrefix + "/include-gpu-index"
+	// PodExcludeGPUIndexAnnotation lists the indexes of the GPUs that must not be allocated to the pod.
+	PodExcludeGPUIndexAnnotation = util.DomainPrefix + "/exclude-gpu-index"
+	// NodeQuarantinedGPUsAnnotation lists the UUIDs or indexes of the GPUs of the node excluded from all new allocations.
+	NodeQuarantinedGPUsAnnotation = util.DomainPrefix + "/quarantined-gpus"
+)
+
+// deviceConstraints are the requirements each GPU allocated to the pod must meet on top of the
+// GPU type and UUID annotations, which the allocator checks itself.
+type deviceConstraints struct {
+	includeIndexes       sets.Set[int]
+	excludeIndexes       sets.Set[int]
+	minMemory            int
+	minComputeCapability float32
+}
+
+func newDeviceConstraints(annotations map[string]string) (deviceConstraints, error) {
+	var (
+		constraints deviceConstraints
+		err         error
+	)
+	if constraints.includeIndexes, err = parseIndexes(annotations, PodIncludeGPUIndexAnnotation); err != nil {
+		return constraints, err
+	}
+	if constraints.excludeIndexes, err = parseIndexes(annotations, PodExcludeGPUIndexAnnotation); err != nil {
+		return constraints, err
+	}
+	if value, ok := annotations[PodGPUMinMemoryAnnotation]; ok {
+		minMemory, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || minMemory < 0 {
+			return constraints, fmt.Errorf("invalid annotation %s value %q, must be a non-negative integer", PodGPUMinMemoryAnnotation, value)
+		}
+		constraints.minMemory = minMemory
+	}
+	if value, ok := annotations[PodGPUMinComputeCapabilityAnnotation]; ok {
+		capability, err := strconv.ParseFloat(strings.TrimSpace(value), 32)
+		if err != nil || capability < 0 {
+			return constraints, fmt.Errorf("invalid annotation %s value %q, must be a version like 8.0", PodGPUMinComputeCapabilityAnnotation, value)
+		}
+		constraints.minComputeCapability = float32(capability)
+	}
+	return constraints, nil
+}
+
+// parseIndexes parses a comma separated list of GPU indexes.
+func parseIndexes(annotations map[string]string, key string) (sets.Set[int], error) {
+	items := splitAnnotationList(annotations, key)
+	if items == nil {
+		return nil, nil
+	}
+	indexes := sets.New[int]()
+	for _, item := range items {
+		index, err := strconv.Atoi(item)
+		if err != nil || index < 0 {
+			return nil, fmt.Errorf("invalid annotation %s value %q, must be a list of GPU indexes", key, annotations[key])
+		}
+		indexes.Insert(index)
+	}
+	return indexes, nil
+}
+
+// matches reports whether the device meets the constraints.
+func (c deviceConstraints) matches(dev *device.Device) bool {
+	if c.includeIndexes != nil && !c.includeIndexes.Has(dev.GetID()) {
+		return false
+	}
+	if c.excludeIndexes.Has(dev.GetID()) {
+		return false
+	}
+	return dev.GetTotalMemory() >= c.minMemory && dev.GetComputeCapability() >= c.minComputeCapability
+}
+
+// quarantinedGPUs returns the upper case UUIDs and indexes of the quarantined GPUs of the node.
+func quarantinedGPUs(node *v1.Node) []string {
+	return splitAnnotationList(node.GetAnnotations(), NodeQuarantinedGPUsAnnotation)
+}
+
+// isListed reports whether the device is listed by exact UUID or index, e.g. among the quarantined GPUs.
+func isListed(list []string, dev *device.Device) bool {
+	return slices.Contains(list, strings.ToUpper(dev.GetUUID())) ||
+		slices.Contains(list, strconv.Itoa(dev.GetID()))
+}
+
+// maskDevices uses up the resources of the unhealthy, quarantined and non matching devices so
+// the allocator skips them, it returns the number of devices left. The node info must be a copy.
+func maskDevices(info *device.NodeInfo, constraints deviceConstraints, quarantined []string) (int, error) {
+	matched := 0
+	for id, dev := range info.GetDeviceMap() {
+		if dev.Healthy() && constraints.matches(dev) && !isListed(quarantined, dev) {
+			matched++
+			continue
+		}
+		if err := maskDevice(info, id, dev); err != nil {
+			return 0, err
+		}
+	}
+	return matched, nil
+}
+
+// maskDevice makes the device unallocatable by using up all its resources.
+func maskDevice(info *device.NodeInfo, id int, dev *device.Device) error {
+	if err := info.AddUsedResources(id, dev.AllocatableCores(), dev.AllocatableMemory()); err != nil {
+		return err
+	}
+	for dev.AllocatableNumber() > 0 {
+		if err := info.AddUsedResources(id, 0, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
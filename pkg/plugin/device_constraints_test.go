@@ -0,0 +1,148 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/device"
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+var _ = Describe("VGPUSchedulerPlugin device constraints", func() {
+	var (
+		plugin    *VGPUSchedulerPlugin
+		ctx       context.Context
+		testState *framework.CycleState
+		testPod   *v1.Pod
+		nodeInfo  *framework.NodeInfo
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		testState = framework.NewCycleState()
+		plugin = &VGPUSchedulerPlugin{
+			handle: &eventHandleStub{recorder: events.NewFakeRecorder(10)},
+			cache:  newDeviceCache(),
+		}
+		testPod = &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test-pod",
+				Namespace:   "default",
+				UID:         uuid.NewUUID(),
+				Annotations: map[string]string{},
+			},
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{{
+					Name: "default",
+					Resources: v1.ResourceRequirements{
+						Limits: v1.ResourceList{
+							util.VGPUNumberResourceName: resource.MustParse("1"),
+							util.VGPUMemoryResourceName: resource.MustParse("1024"),
+						},
+					},
+				}},
+			},
+		}
+		heartbeat, _ := metav1.NowMicro().MarshalText()
+		nodeInfo = framework.NewNodeInfo()
+		nodeInfo.SetNode(&v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "mixed-node",
+				UID:  uuid.NewUUID(),
+				Annotations: map[string]string{
+					util.NodeDeviceHeartbeatAnnotation: string(heartbeat),
+					util.NodeConfigInfoAnnotation:      `{"deviceSplit":10,"coresScaling":1,"memoryFactor":1,"memoryScaling":1}`,
+					util.NodeDeviceRegisterAnnotation: `[` +
+						`{"id":0,"uuid":"GPU-0","type":"NVIDIA Tesla T4","core":100,"memory":15360,"number":10,"capability":7.5,"healthy":true},` +
+						`{"id":1,"uuid":"GPU-1","type":"NVIDIA A100-SXM4-80GB","core":100,"memory":81920,"number":10,"capability":8.0,"healthy":true}]`,
+				},
+			},
+			Status: v1.NodeStatus{
+				Allocatable: v1.ResourceList{util.VGPUNumberResourceName: resource.MustParse("20")},
+			},
+		})
+	})
+
+	allocatedDevices := func() []device.ClaimDevice {
+		data, err := testState.Read(plugin.preAllocateDeviceKey(nodeInfo.GetName()))
+		Expect(err).NotTo(HaveOccurred())
+		podDevices := device.PodDevices{}
+		Expect(podDevices.UnmarshalText(string(data.(preAllocateDevice)))).To(Succeed())
+		Expect(podDevices).To(HaveLen(1))
+		return podDevices[0].Devices
+	}
+
+	DescribeTable("should only allocate the GPUs meeting the requirements",
+		func(annotations map[string]string, uuid string) {
+			testPod.Annotations = annotations
+			_, status := plugin.PreFilter(ctx, testState, testPod)
+			Expect(status.IsSuccess()).To(BeTrue())
+			Expect(plugin.Filter(ctx, testState, testPod, nodeInfo).IsSuccess()).To(BeTrue())
+			devices := allocatedDevices()
+			Expect(devices).To(HaveLen(1))
+			Expect(devices[0].Uuid).To(Equal(uuid))
+		},
+		Entry("included type", map[string]string{util.PodIncludeGpuTypeAnnotation: "t4,h100"}, "GPU-0"),
+		Entry("excluded type", map[string]string{util.PodExcludeGpuTypeAnnotation: "T4"}, "GPU-1"),
+		Entry("min memory", map[string]string{PodGPUMinMemoryAnnotation: "40960"}, "GPU-1"),
+		Entry("min compute capability", map[string]string{PodGPUMinComputeCapabilityAnnotation: "8.0"}, "GPU-1"),
+		Entry("included uuid", map[string]string{util.PodIncludeGPUUUIDAnnotation: "gpu-1"}, "GPU-1"),
+		Entry("excluded uuid", map[string]string{util.PodExcludeGPUUUIDAnnotation: "GPU-1"}, "GPU-0"),
+		Entry("included index", map[string]string{PodIncludeGPUIndexAnnotation: "1, 3"}, "GPU-1"),
+		Entry("excluded index", map[string]string{PodExcludeGPUIndexAnnotation: "1"}, "GPU-0"),
+	)
+
+	It("should not allocate the quarantined GPUs of the node", func() {
+		_, status := plugin.PreFilter(ctx, testState, testPod)
+		Expect(status.IsSuccess()).To(BeTrue())
+
+		nodeInfo.Node().Annotations[NodeQuarantinedGPUsAnnotation] = "gpu-0"
+		Expect(plugin.Filter(ctx, testState, testPod, nodeInfo).IsSuccess()).To(BeTrue())
+		Expect(allocatedDevices()[0].Uuid).To(Equal("GPU-1"))
+
+		nodeInfo.Node().Annotations[NodeQuarantinedGPUsAnnotation] = "GPU-0,1"
+		testState = framework.NewCycleState()
+		_, status = plugin.PreFilter(ctx, testState, testPod)
+		Expect(status.IsSuccess()).To(BeTrue())
+		status = plugin.Filter(ctx, testState, testPod, nodeInfo)
+		Expect(status.Code()).To(Equal(framework.Unschedulable))
+	})
+
+	It("should reject the node when no GPU meets the requirements", func() {
+		testPod.Annotations[PodGPUMinMemoryAnnotation] = "102400"
+		_, status := plugin.PreFilter(ctx, testState, testPod)
+		Expect(status.IsSuccess()).To(BeTrue())
+		status = plugin.Filter(ctx, testState, testPod, nodeInfo)
+		Expect(status.Code()).To(Equal(framework.Unschedulable))
+		Expect(status.Message()).To(Equal("no GPU on the node meets the device requirements of the pod"))
+	})
+
+	It("should not change the node device state", func() {
+		testPod.Annotations[PodGPUMinMemoryAnnotation] = "40960"
+		_, status := plugin.PreFilter(ctx, testState, testPod)
+		Expect(status.IsSuccess()).To(BeTrue())
+		Expect(plugin.Filter(ctx, testState, testPod, nodeInfo).IsSuccess()).To(BeTrue())
+		devNodeInfo, err := plugin.getDevNodeInfo(testState, nodeInfo)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(devNodeInfo.GetDeviceMap()[0].AllocatableMemory()).To(Equal(15360))
+	})
+
+	DescribeTable("should reject invalid requirement annotations in PreFilter",
+		func(annotation, value string) {
+			testPod.Annotations[annotation] = value
+			_, status := plugin.PreFilter(ctx, testState, testPod)
+			Expect(status.Code()).To(Equal(framework.UnschedulableAndUnresolvable))
+			Expect(status.Message()).To(ContainSubstring(annotation))
+		},
+		Entry("compute capability", PodGPUMinComputeCapabilityAnnotation, "ampere"),
+		Entry("min memory", PodGPUMinMemoryAnnotation, "-1"),
+		Entry("gpu index", PodIncludeGPUIndexAnnotation, "0,GPU-1"),
+	)
+})
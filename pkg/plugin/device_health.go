@@ -0,0 +1,137 @@
+package plugin
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/device"
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// gpuHealth returns the health of each GPU of the node by UUID, read from the device registry.
+func gpuHealth(node *v1.Node) map[string]bool {
+	registry, ok := util.HasAnnotation(node, util.NodeDeviceRegisterAnnotation)
+	if !ok {
+		return nil
+	}
+	devices := device.NodeDeviceInfo{}
+	if err := devices.Decode(registry); err != nil {
+		return nil
+	}
+	health := make(map[string]bool, len(devices))
+	for _, dev := range devices {
+		health[strings.ToUpper(dev.Uuid)] = dev.Healthy
+	}
+	return health
+}
+
+// gpuHealthTracker records the recent GPU health transitions of each node.
+type gpuHealthTracker struct {
+	mu     sync.Mutex
+	window time.Duration
+	nodes  map[string]*nodeGPUHealth
+	now    func() time.Time
+}
+
+type nodeGPUHealth struct {
+	health      map[string]bool
+	transitions []time.Time
+}
+
+func newGPUHealthTracker(window time.Duration) *gpuHealthTracker {
+	return &gpuHealthTracker{
+		window: window,
+		nodes:  make(map[string]*nodeGPUHealth),
+		now:    time.Now,
+	}
+}
+
+func (t *gpuHealthTracker) observe(node *v1.Node) {
+	health := gpuHealth(node)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry, ok := t.nodes[node.Name]
+	if !ok {
+		t.nodes[node.Name] = &nodeGPUHealth{health: health}
+		return
+	}
+	now := t.now()
+	for uuid, healthy := range health {
+		if wasHealthy, ok := entry.health[uuid]; ok && wasHealthy != healthy {
+			klog.V(4).InfoS("GPU health changed", "node", node.Name, "uuid", uuid, "healthy", healthy)
+			entry.transitions = append(entry.transitions, now)
+		}
+	}
+	entry.health = health
+	entry.transitions = t.pruneLocked(entry.transitions, now)
+}
+
+func (t *gpuHealthTracker) pruneLocked(transitions []time.Time, now time.Time) []time.Time {
+	for len(transitions) > 0 && now.Sub(transitions[0]) > t.window {
+		transitions = transitions[1:]
+	}
+	return transitions
+}
+
+func (t *gpuHealthTracker) forget(nodeName string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.nodes, nodeName)
+}
+
+func (t *gpuHealthTracker) flaps(nodeName string) int {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry, ok := t.nodes[nodeName]
+	if !ok {
+		return 0
+	}
+	entry.transitions = t.pruneLocked(entry.transitions, t.now())
+	return len(entry.transitions)
+}
+
+func (t *gpuHealthTracker) nodeEventHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if node, ok := obj.(*v1.Node); ok {
+				t.observe(node)
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			if node, ok := newObj.(*v1.Node); ok {
+				t.observe(node)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			switch o := obj.(type) {
+			case *v1.Node:
+				t.forget(o.Name)
+			case cache.DeletedFinalStateUnknown:
+				if node, ok := o.Obj.(*v1.Node); ok {
+					t.forget(node.Name)
+				}
+			}
+		},
+	}
+}
+
+func (p *VGPUSchedulerPlugin) applyFlappingPenalty(nodeName string, score int64) int64 {
+	if p.gpuFlappingPenaltyPercent == 0 {
+		return score
+	}
+	flaps := p.health.flaps(nodeName)
+	if flaps == 0 {
+		return score
+	}
+	penalty := (score*p.gpuFlappingPenaltyPercent + 99) / 100
+	klog.V(4).Infof("Applying %d%% flapping GPU penalty (%d) for node %s with %d GPU health changes",
+		p.gpuFlappingPenaltyPercent, penalty, nodeName, flaps)
+	return max(score-penalty, 0)
+}
@@ -0,0 +1,129 @@
+package plugin
+
+import (
+	"context"
+	"time"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+var _ = Describe("VGPUSchedulerPlugin GPU health", func() {
+	const (
+		healthyRegistry   = `[{"id":0,"uuid":"GPU-0","core":100,"memory":10240,"number":10,"healthy":true},{"id":1,"uuid":"GPU-1","core":100,"memory":10240,"number":10,"healthy":true}]`
+		unhealthyRegistry = `[{"id":0,"uuid":"GPU-0","core":100,"memory":10240,"number":10,"healthy":true},{"id":1,"uuid":"GPU-1","core":100,"memory":10240,"number":10,"healthy":false}]`
+	)
+	var testNode *v1.Node
+
+	BeforeEach(func() {
+		heartbeat, _ := metav1.NowMicro().MarshalText()
+		testNode = &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-node",
+				UID:  uuid.NewUUID(),
+				Annotations: map[string]string{
+					util.NodeDeviceHeartbeatAnnotation: string(heartbeat),
+					util.NodeConfigInfoAnnotation:      `{"deviceSplit":10,"coresScaling":1,"memoryFactor":1,"memoryScaling":1}`,
+					util.NodeDeviceRegisterAnnotation:  healthyRegistry,
+				},
+			},
+			Status: v1.NodeStatus{
+				Allocatable: v1.ResourceList{util.VGPUNumberResourceName: resource.MustParse("20")},
+			},
+		}
+	})
+
+	It("should read the GPU health from the device registry", func() {
+		Expect(gpuHealth(testNode)).To(Equal(map[string]bool{"GPU-0": true, "GPU-1": true}))
+		testNode.Annotations[util.NodeDeviceRegisterAnnotation] = unhealthyRegistry
+		Expect(gpuHealth(testNode)).To(Equal(map[string]bool{"GPU-0": true, "GPU-1": false}))
+	})
+
+	It("should count the health transitions within the flapping window", func() {
+		now := time.Now()
+		tracker := newGPUHealthTracker(10 * time.Minute)
+		tracker.now = func() time.Time { return now }
+
+		tracker.observe(testNode)
+		Expect(tracker.flaps(testNode.Name)).To(Equal(0))
+		unhealthyNode := testNode.DeepCopy()
+		unhealthyNode.Annotations[util.NodeDeviceRegisterAnnotation] = unhealthyRegistry
+		tracker.observe(unhealthyNode)
+		now = now.Add(time.Minute)
+		tracker.observe(testNode)
+		Expect(tracker.flaps(testNode.Name)).To(Equal(2))
+
+		now = now.Add(9*time.Minute + time.Second)
+		Expect(tracker.flaps(testNode.Name)).To(Equal(1))
+		now = now.Add(time.Minute)
+		Expect(tracker.flaps(testNode.Name)).To(Equal(0))
+
+		tracker.observe(unhealthyNode)
+		tracker.forget(testNode.Name)
+		Expect(tracker.flaps(testNode.Name)).To(Equal(0))
+	})
+
+	It("should take the flapping penalty from the node score", func() {
+		plugin := &VGPUSchedulerPlugin{health: newGPUHealthTracker(time.Minute), gpuFlappingPenaltyPercent: 20}
+		plugin.health.observe(testNode)
+		Expect(plugin.applyFlappingPenalty(testNode.Name, 50)).To(Equal(int64(50)))
+		unhealthyNode := testNode.DeepCopy()
+		unhealthyNode.Annotations[util.NodeDeviceRegisterAnnotation] = unhealthyRegistry
+		plugin.health.observe(unhealthyNode)
+		Expect(plugin.applyFlappingPenalty(testNode.Name, 50)).To(Equal(int64(40)))
+		plugin.gpuFlappingPenaltyPercent = 0
+		Expect(plugin.applyFlappingPenalty(testNode.Name, 50)).To(Equal(int64(50)))
+	})
+
+	Context("Filter", func() {
+		var (
+			plugin    *VGPUSchedulerPlugin
+			ctx       context.Context
+			testState *framework.CycleState
+			testPod   *v1.Pod
+			nodeInfo  *framework.NodeInfo
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			testState = framework.NewCycleState()
+			plugin = &VGPUSchedulerPlugin{
+				handle: &eventHandleStub{recorder: events.NewFakeRecorder(10)},
+				cache:  newDeviceCache(),
+			}
+			testPod = &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default", UID: uuid.NewUUID()},
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{{
+						Name: "default",
+						Resources: v1.ResourceRequirements{
+							Limits: v1.ResourceList{
+								util.VGPUNumberResourceName: resource.MustParse("2"),
+								util.VGPUMemoryResourceName: resource.MustParse("1024"),
+							},
+						},
+					}},
+				},
+			}
+			nodeInfo = framework.NewNodeInfo()
+			nodeInfo.SetNode(testNode)
+			_, status := plugin.PreFilter(ctx, testState, testPod)
+			Expect(status.IsSuccess()).To(BeTrue())
+		})
+
+		It("should not allocate the GPUs unhealthy in the device registry", func() {
+			Expect(plugin.Filter(ctx, testState, testPod, nodeInfo).IsSuccess()).To(BeTrue())
+			testNode.Annotations[util.NodeDeviceRegisterAnnotation] = unhealthyRegistry
+			plugin.cache.updateNode(testNode)
+			plugin.deleteDevNodeInfo(testState, nodeInfo)
+			Expect(plugin.Filter(ctx, testState, testPod, nodeInfo).IsSuccess()).To(BeFalse())
+		})
+	})
+})
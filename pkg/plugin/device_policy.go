@@ -0,0 +1,72 @@
+package plugin
+
+import (
+	"maps"
+	"strings"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/device"
+	"github.com/coldzerofear/vgpu-manager/pkg/device/allocator"
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	v1 "k8s.io/api/core/v1"
+)
+
+// devicePolicies are the device scheduling policies, prefer-idle is implemented by the plugin.
+var devicePolicies = []string{
+	string(util.NonePolicy),
+	string(util.BinpackPolicy),
+	string(util.SpreadPolicy),
+	PreferIdlePolicy,
+}
+
+// allocateDevices returns a copy of the pod annotated with the devices allocated by its device policy.
+func allocateDevices(info *device.NodeInfo, pod *v1.Pod, request *podRequest) (*v1.Pod, error) {
+	if request.devicePolicy != PreferIdlePolicy {
+		return allocator.NewAllocator(info).Allocate(podForAllocation(pod, request.devicePolicy))
+	}
+	// Fall back to spreading the pod when the idle GPUs cannot hold it.
+	allocPod := podForAllocation(pod, string(util.SpreadPolicy))
+	idleInfo := info.Clone().(*device.NodeInfo)
+	idle, err := maskUsedDevices(idleInfo)
+	if err != nil {
+		return nil, err
+	}
+	if idle > 0 {
+		if newPod, err := allocator.NewAllocator(idleInfo).Allocate(allocPod); err == nil {
+			return newPod, nil
+		}
+	}
+	return allocator.NewAllocator(info).Allocate(allocPod)
+}
+
+// podForAllocation returns the pod annotated with the policy, the allocator reads it from the annotations.
+func podForAllocation(pod *v1.Pod, policy string) *v1.Pod {
+	if policy == "" || policy == string(util.NonePolicy) ||
+		strings.ToLower(pod.Annotations[util.DeviceSchedulerPolicyAnnotation]) == policy {
+		return pod
+	}
+	allocPod := *pod
+	allocPod.Annotations = maps.Clone(pod.Annotations)
+	if allocPod.Annotations == nil {
+		allocPod.Annotations = map[string]string{}
+	}
+	allocPod.Annotations[util.DeviceSchedulerPolicyAnnotation] = policy
+	return &allocPod
+}
+
+// maskUsedDevices uses up the resources of the partially used devices and returns the number of idle ones.
+func maskUsedDevices(info *device.NodeInfo) (int, error) {
+	idle := 0
+	for id, dev := range info.GetDeviceMap() {
+		if dev.AllocatableNumber() == 0 {
+			continue
+		}
+		if dev.AllocatableNumber() == dev.GetTotalNumber() {
+			idle++
+			continue
+		}
+		if err := maskDevice(info, id, dev); err != nil {
+			return 0, err
+		}
+	}
+	return idle, nil
+}
@@ -0,0 +1,98 @@
+package plugin
+
+import (
+	"github.com/coldzerofear/vgpu-manager/pkg/device"
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+var _ = Describe("VGPUSchedulerPlugin device policies", func() {
+	var (
+		testPod  *v1.Pod
+		nodeInfo *device.NodeInfo
+	)
+
+	BeforeEach(func() {
+		testPod = &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test-pod",
+				Namespace:   "default",
+				UID:         uuid.NewUUID(),
+				Annotations: map[string]string{},
+			},
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{{
+					Name: "gpu",
+					Resources: v1.ResourceRequirements{
+						Limits: v1.ResourceList{
+							util.VGPUNumberResourceName: resource.MustParse("1"),
+							util.VGPUCoreResourceName:   resource.MustParse("10"),
+							util.VGPUMemoryResourceName: resource.MustParse("1024"),
+						},
+					},
+				}},
+			},
+		}
+		heartbeat, _ := metav1.NowMicro().MarshalText()
+		node := &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-node",
+				Annotations: map[string]string{
+					util.NodeDeviceHeartbeatAnnotation: string(heartbeat),
+					util.NodeConfigInfoAnnotation:      `{"deviceSplit":10,"coresScaling":1,"memoryFactor":1,"memoryScaling":1}`,
+					util.NodeDeviceRegisterAnnotation: `[{"id":0,"uuid":"GPU-0","core":100,"memory":10240,"number":10,"healthy":true},` +
+						`{"id":1,"uuid":"GPU-1","core":100,"memory":10240,"number":10,"healthy":true},` +
+						`{"id":2,"uuid":"GPU-2","core":100,"memory":10240,"number":10,"healthy":true}]`,
+				},
+			},
+		}
+		var err error
+		nodeInfo, err = device.NewNodeInfo(node, nil)
+		Expect(err).NotTo(HaveOccurred())
+		// GPU-0 runs two vGPUs and GPU-1 one, GPU-2 is idle.
+		Expect(nodeInfo.AddUsedResources(0, 40, 4096)).To(Succeed())
+		Expect(nodeInfo.AddUsedResources(0, 40, 4096)).To(Succeed())
+		Expect(nodeInfo.AddUsedResources(1, 10, 1024)).To(Succeed())
+	})
+
+	allocatedGPU := func(policy string) string {
+		request := newPodRequest(testPod, schedulingPolicies{devicePolicy: policy})
+		newPod, err := allocateDevices(nodeInfo.Clone().(*device.NodeInfo), testPod, request)
+		Expect(err).NotTo(HaveOccurred())
+		podDevices := device.GetPodAssignDevices(newPod)
+		Expect(podDevices).To(HaveLen(1))
+		Expect(podDevices[0].Devices).To(HaveLen(1))
+		return podDevices[0].Devices[0].Uuid
+	}
+
+	It("should allocate the GPUs following the device policy", func() {
+		Expect(allocatedGPU(string(util.BinpackPolicy))).To(Equal("GPU-0"))
+		Expect(allocatedGPU(string(util.SpreadPolicy))).To(Equal("GPU-2"))
+		Expect(allocatedGPU(PreferIdlePolicy)).To(Equal("GPU-2"))
+		Expect(testPod.Annotations).NotTo(HaveKey(util.DeviceSchedulerPolicyAnnotation))
+	})
+
+	It("should prefer the idle GPUs and fall back to the least used GPUs", func() {
+		// The idle GPU is left to the pods that need it, the used GPUs are packed otherwise.
+		testPod.Annotations[util.DeviceSchedulerPolicyAnnotation] = PreferIdlePolicy
+		Expect(allocatedGPU(string(util.BinpackPolicy))).To(Equal("GPU-2"))
+
+		Expect(nodeInfo.AddUsedResources(2, 50, 8192)).To(Succeed())
+		Expect(allocatedGPU(string(util.NonePolicy))).To(Equal("GPU-1"))
+	})
+
+	It("should hand the device policy to the allocator", func() {
+		allocPod := podForAllocation(testPod, string(util.BinpackPolicy))
+		Expect(allocPod.Annotations).To(HaveKeyWithValue(util.DeviceSchedulerPolicyAnnotation, string(util.BinpackPolicy)))
+		Expect(testPod.Annotations).NotTo(HaveKey(util.DeviceSchedulerPolicyAnnotation))
+		Expect(podForAllocation(testPod, string(util.NonePolicy))).To(BeIdenticalTo(testPod))
+
+		testPod.Annotations[util.DeviceSchedulerPolicyAnnotation] = "Spread"
+		Expect(podForAllocation(testPod, string(util.SpreadPolicy))).To(BeIdenticalTo(testPod))
+	})
+})
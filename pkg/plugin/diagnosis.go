@@ -0,0 +1,165 @@
+package plugin
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/device"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	filterDiagnosisKey framework.StateKey = "FilterDiagnosis"
+
+	// maxDiagnosisNodesPerReason is the number of nodes listed for each reason in the summary.
+	maxDiagnosisNodesPerReason = 3
+	// maxDiagnosisSummaryLength is the maximum length of the summary, the note of an event is limited to 1KB.
+	maxDiagnosisSummaryLength = 1024
+)
+
+// gpuFreeResources is the free resources of a GPU on a node rejected by Filter.
+type gpuFreeResources struct {
+	ID      int    `json:"id"`
+	Type    string `json:"type"`
+	Healthy bool   `json:"healthy"`
+	Number  int    `json:"number"`
+	Cores   int    `json:"cores"`
+	Memory  int    `json:"memory"`
+}
+
+// nodeDiagnosis is why a node was rejected by Filter.
+type nodeDiagnosis struct {
+	Reason string             `json:"reason"`
+	GPUs   []gpuFreeResources `json:"gpus,omitempty"`
+}
+
+// filterDiagnosis records the Filter failures of the nodes for the pod, it is written
+// in PreFilter and updated concurrently by Filter.
+type filterDiagnosis struct {
+	mu    sync.Mutex
+	nodes map[string]nodeDiagnosis
+}
+
+// Clone copies the diagnosis, so that the Filter failures simulated by preemption
+// are not recorded as failures of the scheduling cycle.
+func (d *filterDiagnosis) Clone() framework.StateData {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return &filterDiagnosis{nodes: maps.Clone(d.nodes)}
+}
+
+func getFilterDiagnosis(state *framework.CycleState) *filterDiagnosis {
+	if data, err := state.Read(filterDiagnosisKey); err == nil {
+		return data.(*filterDiagnosis)
+	}
+	return nil
+}
+
+// recordFilterFailure records the reason of the node failure, together with the free
+// resources of the GPUs of the node when its devices were evaluated.
+func (p *VGPUSchedulerPlugin) recordFilterFailure(state *framework.CycleState, nodeName string, status *framework.Status) {
+	diagnosis := getFilterDiagnosis(state)
+	if diagnosis == nil {
+		return
+	}
+	result := nodeDiagnosis{Reason: status.Message()}
+	if data, err := state.Read(devNodeInfoKey(nodeName)); err == nil {
+		deviceMap := data.(*device.NodeInfo).GetDeviceMap()
+		for _, id := range slices.Sorted(maps.Keys(deviceMap)) {
+			dev := deviceMap[id]
+			result.GPUs = append(result.GPUs, gpuFreeResources{
+				ID:      id,
+				Type:    dev.GetType(),
+				Healthy: dev.Healthy(),
+				Number:  dev.AllocatableNumber(),
+				Cores:   dev.AllocatableCores(),
+				Memory:  dev.AllocatableMemory(),
+			})
+		}
+	}
+	diagnosis.mu.Lock()
+	defer diagnosis.mu.Unlock()
+	diagnosis.nodes[nodeName] = result
+}
+
+// summary returns a compact description of the request and of the node failures grouped by reason.
+func (d *filterDiagnosis) summary(request *podRequest) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var builder strings.Builder
+	containers := make([]string, 0, len(request.containers))
+	for _, c := range request.containers {
+		containers = append(containers, fmt.Sprintf("%s: %d GPU, %d cores, %d memory", c.Name, c.Number, c.Cores, c.Memory))
+	}
+	fmt.Fprintf(&builder, "vGPU request [%s] does not fit on %d nodes:", strings.Join(containers, "; "), len(d.nodes))
+	reasonNodes := map[string][]string{}
+	for name, diagnosis := range d.nodes {
+		reasonNodes[diagnosis.Reason] = append(reasonNodes[diagnosis.Reason], name)
+	}
+	reasons := slices.Collect(maps.Keys(reasonNodes))
+	// List the most common reasons first.
+	slices.SortFunc(reasons, func(a, b string) int {
+		if n := len(reasonNodes[b]) - len(reasonNodes[a]); n != 0 {
+			return n
+		}
+		return strings.Compare(a, b)
+	})
+	for _, reason := range reasons {
+		nodes := reasonNodes[reason]
+		slices.Sort(nodes)
+		fmt.Fprintf(&builder, " %s (%d nodes:", reason, len(nodes))
+		for i, name := range nodes {
+			if i == maxDiagnosisNodesPerReason {
+				builder.WriteString(" ...")
+				break
+			}
+			fmt.Fprintf(&builder, " %s", name)
+			if gpus := d.nodes[name].GPUs; len(gpus) > 0 {
+				free := make([]string, 0, len(gpus))
+				for _, gpu := range gpus {
+					if !gpu.Healthy {
+						free = append(free, fmt.Sprintf("%d:unhealthy", gpu.ID))
+						continue
+					}
+					free = append(free, fmt.Sprintf("%d:%dc/%dMi", gpu.ID, gpu.Cores, gpu.Memory))
+				}
+				fmt.Fprintf(&builder, "[%s]", strings.Join(free, ","))
+			}
+		}
+		builder.WriteString(");")
+	}
+	summary := strings.TrimSuffix(builder.String(), ";")
+	if len(summary) > maxDiagnosisSummaryLength {
+		// Cut on a rune boundary, the reasons are not necessarily ASCII.
+		end := maxDiagnosisSummaryLength - 3
+		for end > 0 && !utf8.RuneStart(summary[end]) {
+			end--
+		}
+		summary = summary[:end] + "..."
+	}
+	return summary
+}
+
+// reportFilterDiagnosis writes the summary of the node failures to an event of the pod.
+func (p *VGPUSchedulerPlugin) reportFilterDiagnosis(state *framework.CycleState, pod *v1.Pod) {
+	diagnosis := getFilterDiagnosis(state)
+	if diagnosis == nil {
+		return
+	}
+	diagnosis.mu.Lock()
+	nodes := maps.Clone(diagnosis.nodes)
+	diagnosis.mu.Unlock()
+	if len(nodes) == 0 {
+		return
+	}
+	summary := diagnosis.summary(p.getPodRequest(state, pod))
+	klog.V(4).InfoS("vGPU filter diagnosis", "pod", klog.KObj(pod), "summary", summary)
+	klog.V(5).InfoS("vGPU filter diagnosis details", "pod", klog.KObj(pod), "nodes", nodes)
+	p.handle.EventRecorder().Eventf(pod, nil, v1.EventTypeWarning, "VGPUUnschedulable", "Scheduling", summary)
+}
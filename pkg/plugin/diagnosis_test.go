@@ -0,0 +1,105 @@
+package plugin
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+var _ = Describe("VGPUSchedulerPlugin filter diagnosis", func() {
+	var (
+		plugin    *VGPUSchedulerPlugin
+		handle    *eventHandleStub
+		ctx       context.Context
+		testState *framework.CycleState
+		testPod   *v1.Pod
+	)
+
+	newNodeInfo := func(name string, annotations map[string]string, number string) *framework.NodeInfo {
+		nodeInfo := framework.NewNodeInfo()
+		nodeInfo.SetNode(&v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, UID: uuid.NewUUID(), Annotations: annotations},
+			Status: v1.NodeStatus{
+				Allocatable: v1.ResourceList{util.VGPUNumberResourceName: resource.MustParse(number)},
+			},
+		})
+		return nodeInfo
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		testState = framework.NewCycleState()
+		handle = &eventHandleStub{recorder: events.NewFakeRecorder(10)}
+		plugin = &VGPUSchedulerPlugin{handle: handle, cache: newDeviceCache()}
+		testPod = &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default", UID: uuid.NewUUID()},
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{{
+					Name: "default",
+					Resources: v1.ResourceRequirements{
+						Limits: v1.ResourceList{
+							util.VGPUNumberResourceName: resource.MustParse("1"),
+							util.VGPUMemoryResourceName: resource.MustParse("20480"),
+						},
+					},
+				}},
+			},
+		}
+		_, status := plugin.PreFilter(ctx, testState, testPod)
+		Expect(status.IsSuccess()).To(BeTrue())
+	})
+
+	It("should record the failure of each node and report a summary", func() {
+		heartbeat, _ := metav1.NowMicro().MarshalText()
+		gpuNode := newNodeInfo("gpu-node", map[string]string{
+			util.NodeDeviceHeartbeatAnnotation: string(heartbeat),
+			util.NodeConfigInfoAnnotation:      `{"deviceSplit":10,"coresScaling":1,"memoryFactor":1,"memoryScaling":1}`,
+			util.NodeDeviceRegisterAnnotation:  `[{"id":0,"uuid":"GPU-0","core":100,"memory":10240,"number":10,"healthy":true}]`,
+		}, "10")
+		cpuNode := newNodeInfo("cpu-node", nil, "0")
+
+		Expect(plugin.Filter(ctx, testState, testPod, gpuNode).IsSuccess()).To(BeFalse())
+		Expect(plugin.Filter(ctx, testState, testPod, cpuNode).IsSuccess()).To(BeFalse())
+
+		diagnosis := getFilterDiagnosis(testState)
+		Expect(diagnosis.nodes).To(HaveLen(2))
+		Expect(diagnosis.nodes["cpu-node"].Reason).To(Equal("node does not have GPU"))
+		Expect(diagnosis.nodes["gpu-node"].GPUs).To(Equal([]gpuFreeResources{
+			{ID: 0, Healthy: true, Number: 10, Cores: 100, Memory: 10240},
+		}))
+
+		plugin.reportFilterDiagnosis(testState, testPod)
+		var event string
+		Expect(handle.recorder.Events).To(Receive(&event))
+		Expect(event).To(ContainSubstring("VGPUUnschedulable"))
+		Expect(event).To(ContainSubstring("default: 1 GPU, 0 cores, 20480 memory"))
+		Expect(event).To(ContainSubstring("gpu-node[0:100c/10240Mi]"))
+		Expect(event).To(ContainSubstring("node does not have GPU (1 nodes: cpu-node)"))
+	})
+
+	It("should not record the failures simulated on a cloned state", func() {
+		cloned := testState.Clone()
+		Expect(plugin.Filter(ctx, cloned, testPod, newNodeInfo("cpu-node", nil, "0")).IsSuccess()).To(BeFalse())
+		Expect(getFilterDiagnosis(testState).nodes).To(BeEmpty())
+	})
+
+	It("should truncate the summary on a rune boundary", func() {
+		diagnosis := &filterDiagnosis{nodes: map[string]nodeDiagnosis{
+			"gpu-node": {Reason: strings.Repeat("节点", maxDiagnosisSummaryLength)},
+		}}
+		summary := diagnosis.summary(&podRequest{})
+		Expect(len(summary)).To(BeNumerically("<=", maxDiagnosisSummaryLength))
+		Expect(utf8.ValidString(summary)).To(BeTrue())
+		Expect(summary).To(HaveSuffix("..."))
+	})
+})
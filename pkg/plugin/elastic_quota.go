@@ -0,0 +1,306 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	pluginconfig "github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/apis/config"
+	"github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/apis/config/validation"
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// BorrowedPodLabel marks the vGPU pods scheduled with resources borrowed from other
+	// elastic quotas, they are preempted first when the owners reclaim their resources.
+	BorrowedPodLabel = util.DomainPrefix + "/vgpu-quota-borrowed"
+
+	elasticQuotasConfigMapKey = "elasticQuotas"
+
+	elasticQuotaStateKey framework.StateKey = "ElasticQuota"
+)
+
+// elasticQuota is the parsed form of pluginconfig.ElasticQuota.
+type elasticQuota struct {
+	name       string
+	namespaces sets.Set[string]
+	min        pluginconfig.VGPUResourceList
+	max        pluginconfig.VGPUResourceList
+}
+
+// elasticQuotaManager holds the elastic quotas read from the ConfigMap.
+type elasticQuotaManager struct {
+	mu     sync.RWMutex
+	quotas []*elasticQuota
+	// guaranteed is the sum of the min resources of all quotas.
+	guaranteed pluginconfig.VGPUResourceList
+	// synced reports whether the ConfigMap has been read.
+	synced cache.InformerSynced
+}
+
+func newElasticQuotaManager() *elasticQuotaManager {
+	return &elasticQuotaManager{}
+}
+
+// update replaces the elastic quotas with the ones of the ConfigMap.
+func (m *elasticQuotaManager) update(cm *v1.ConfigMap) error {
+	var config []pluginconfig.ElasticQuota
+	if err := yaml.Unmarshal([]byte(cm.Data[elasticQuotasConfigMapKey]), &config); err != nil {
+		return fmt.Errorf("decoding elastic quotas: %w", err)
+	}
+	if err := validation.ValidateElasticQuotas(field.NewPath(elasticQuotasConfigMapKey), config); err != nil {
+		return fmt.Errorf("invalid elastic quotas: %w", err)
+	}
+	var guaranteed pluginconfig.VGPUResourceList
+	sum := func(total **int64, value *int64) {
+		if value != nil {
+			*total = ptr.To(ptr.Deref(*total, 0) + *value)
+		}
+	}
+	for _, quota := range config {
+		sum(&guaranteed.Number, quota.Min.Number)
+		sum(&guaranteed.Cores, quota.Min.Cores)
+		sum(&guaranteed.Memory, quota.Min.Memory)
+	}
+	// A resource guaranteed by any quota is elastic, quotas not guaranteeing it have a min of 0.
+	minOrZero := func(min, total *int64) *int64 {
+		if min == nil && total != nil {
+			return ptr.To[int64](0)
+		}
+		return min
+	}
+	quotas := make([]*elasticQuota, 0, len(config))
+	for _, quota := range config {
+		quotas = append(quotas, &elasticQuota{
+			name:       quota.Name,
+			namespaces: sets.New(quota.Namespaces...),
+			min: pluginconfig.VGPUResourceList{
+				Number: minOrZero(quota.Min.Number, guaranteed.Number),
+				Cores:  minOrZero(quota.Min.Cores, guaranteed.Cores),
+				Memory: minOrZero(quota.Min.Memory, guaranteed.Memory),
+			},
+			max: quota.Max,
+		})
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quotas, m.guaranteed = quotas, guaranteed
+	return nil
+}
+
+func (m *elasticQuotaManager) clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quotas, m.guaranteed = nil, pluginconfig.VGPUResourceList{}
+}
+
+func (m *elasticQuotaManager) list() ([]*elasticQuota, pluginconfig.VGPUResourceList) {
+	if m == nil {
+		return nil, pluginconfig.VGPUResourceList{}
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.quotas, m.guaranteed
+}
+
+// quotaOf returns the elastic quota of the namespace, or nil when it has none.
+func (m *elasticQuotaManager) quotaOf(namespace string) *elasticQuota {
+	quotas, _ := m.list()
+	for _, quota := range quotas {
+		if quota.namespaces.Has(namespace) {
+			return quota
+		}
+	}
+	return nil
+}
+
+// configMapEventHandler returns the event handler that keeps the elastic quotas up to date
+// with the ConfigMap, given as namespace/name.
+func (m *elasticQuotaManager) configMapEventHandler(configMap string) cache.ResourceEventHandler {
+	update := func(obj interface{}) {
+		cm, ok := obj.(*v1.ConfigMap)
+		if !ok {
+			return
+		}
+		if err := m.update(cm); err != nil {
+			klog.ErrorS(err, "updating elastic quotas failed, keeping the previous ones", "configMap", klog.KObj(cm))
+			return
+		}
+		klog.V(3).InfoS("Updated elastic quotas", "configMap", klog.KObj(cm))
+	}
+	return cache.FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+			return err == nil && key == configMap
+		},
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc:    update,
+			UpdateFunc: func(_, newObj interface{}) { update(newObj) },
+			DeleteFunc: func(interface{}) { m.clear() },
+		},
+	}
+}
+
+// waitForSync starts the ConfigMap informer and waits until the elastic quotas are read.
+func (m *elasticQuotaManager) waitForSync(ctx context.Context, informerFactory informers.SharedInformerFactory) error {
+	informerFactory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), m.synced) {
+		return fmt.Errorf("waiting for the elastic quotas: %w", ctx.Err())
+	}
+	return nil
+}
+
+// elasticQuotaState is the elastic quota status of the pod being scheduled.
+type elasticQuotaState struct {
+	quota string
+	// borrowing is whether the pod needs resources beyond the min of its quota.
+	borrowing bool
+	// overused are the other quotas using more than their min,
+	// whose borrowed pods can be preempted by a pod that is not borrowing.
+	overused sets.Set[string]
+}
+
+// Clone returns the state itself as it is immutable.
+func (s *elasticQuotaState) Clone() framework.StateData {
+	return s
+}
+
+func getElasticQuotaState(state *framework.CycleState) *elasticQuotaState {
+	if data, err := state.Read(elasticQuotaStateKey); err == nil {
+		return data.(*elasticQuotaState)
+	}
+	return nil
+}
+
+// elasticQuotaUsages returns the vGPU resources used by the pods of each elastic quota.
+func (p *VGPUSchedulerPlugin) elasticQuotaUsages(quotas []*elasticQuota, pod *v1.Pod) (map[string]vgpuUsage, error) {
+	usages := make(map[string]vgpuUsage, len(quotas))
+	for _, quota := range quotas {
+		pods, err := p.listQuotaPods(quota.namespaces, labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		var usage vgpuUsage
+		for _, quotaPod := range pods {
+			if quotaPod.UID == pod.UID || !quota.namespaces.Has(quotaPod.Namespace) || accountedNodeName(quotaPod) == "" {
+				continue
+			}
+			usage.add(podRequestedUsage(quotaPod))
+		}
+		usages[quota.name] = usage
+	}
+	return usages, nil
+}
+
+// checkElasticQuota checks the pod against the max of its elastic quota, and when the pod
+// exceeds the min of the quota, that enough guaranteed resources are idle to be borrowed.
+func (p *VGPUSchedulerPlugin) checkElasticQuota(ctx context.Context, state *framework.CycleState, pod *v1.Pod, request *podRequest) *framework.Status {
+	quotas, guaranteed := p.elasticQuotas.list()
+	quota := p.elasticQuotas.quotaOf(pod.Namespace)
+	if quota == nil {
+		return nil
+	}
+	logger := klog.FromContext(ctx)
+	usages, err := p.elasticQuotaUsages(quotas, pod)
+	if err != nil {
+		logger.Error(err, "computing elastic quota usages failed", "pod", klog.KObj(pod))
+		return framework.NewStatus(framework.Error, err.Error())
+	}
+	requested := request.requestedUsage()
+	used := usages[quota.name]
+	used.add(requested)
+	if resource, value, limit, ok := used.exceeds(quota.max); ok {
+		msg := fmt.Sprintf("vGPU elastic quota %s exceeded: %s would be %d, max %d", quota.name, resource, value, limit)
+		logger.Info("vGPU elastic quota exceeded", "pod", klog.KObj(pod), "quota", quota.name, "resource", resource)
+		p.handle.EventRecorder().Eventf(pod, nil, v1.EventTypeWarning, "VGPUQuotaExceeded", "Scheduling", msg)
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, msg)
+	}
+	quotaState := &elasticQuotaState{quota: quota.name, overused: sets.New[string]()}
+	if _, _, _, quotaState.borrowing = used.exceeds(quota.min); quotaState.borrowing {
+		total := requested
+		for _, usage := range usages {
+			total.add(usage)
+		}
+		if resource, value, limit, ok := total.exceeds(guaranteed); ok {
+			msg := fmt.Sprintf("vGPU elastic quota %s cannot borrow: total %s would be %d, guaranteed %d", quota.name, resource, value, limit)
+			logger.V(4).Info("no idle guaranteed vGPU resources to borrow", "pod", klog.KObj(pod), "quota", quota.name, "resource", resource)
+			// PostFilter may still preempt the pods of the own quota, and only those.
+			state.Write(elasticQuotaStateKey, quotaState)
+			return framework.NewStatus(framework.Unschedulable, msg)
+		}
+	} else {
+		for _, other := range quotas {
+			if _, _, _, ok := usages[other.name].exceeds(other.min); ok && other != quota {
+				quotaState.overused.Insert(other.name)
+			}
+		}
+	}
+	state.Write(elasticQuotaStateKey, quotaState)
+	return nil
+}
+
+// elasticQuotaFitsWithout reports whether the pod passes the checks of checkElasticQuota
+// once the removed pods no longer use their resources.
+func (p *VGPUSchedulerPlugin) elasticQuotaFitsWithout(usages map[string]vgpuUsage, pod *v1.Pod, request *podRequest, removed []*v1.Pod) bool {
+	_, guaranteed := p.elasticQuotas.list()
+	quota := p.elasticQuotas.quotaOf(pod.Namespace)
+	if quota == nil {
+		return true
+	}
+	requested := request.requestedUsage()
+	used, total := usages[quota.name], requested
+	used.add(requested)
+	for _, usage := range usages {
+		total.add(usage)
+	}
+	for _, removedPod := range removed {
+		removedQuota := p.elasticQuotas.quotaOf(removedPod.Namespace)
+		if removedQuota == nil {
+			continue
+		}
+		usage := podRequestedUsage(removedPod)
+		if removedQuota.name == quota.name {
+			used.sub(usage)
+		}
+		total.sub(usage)
+	}
+	if _, _, _, ok := used.exceeds(quota.max); ok {
+		return false
+	}
+	if _, _, _, borrowing := used.exceeds(quota.min); borrowing {
+		_, _, _, ok := total.exceeds(guaranteed)
+		return !ok
+	}
+	return true
+}
+
+func isBorrowedPod(pod *v1.Pod) bool {
+	return pod.Labels[BorrowedPodLabel] == "true"
+}
+
+// isElasticQuotaVictim returns whether the pod may be preempted for the preemptor because of
+// elastic quotas: borrowing preemptors only preempt lower priority pods of their own quota,
+// the other preemptors reclaim the borrowed pods of overused quotas regardless of priority.
+func (p *VGPUSchedulerPlugin) isElasticQuotaVictim(quotaState *elasticQuotaState, pod *v1.Pod, lowerPriority bool) bool {
+	if quotaState == nil {
+		return lowerPriority
+	}
+	quota := p.elasticQuotas.quotaOf(pod.Namespace)
+	if quotaState.borrowing {
+		return lowerPriority && quota != nil && quota.name == quotaState.quota
+	}
+	if lowerPriority {
+		return true
+	}
+	return quota != nil && quotaState.overused.Has(quota.name) && isBorrowedPod(pod)
+}
@@ -0,0 +1,229 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("VGPUSchedulerPlugin elastic quotas", func() {
+	var (
+		plugin    *VGPUSchedulerPlugin
+		indexer   cache.Indexer
+		ctx       context.Context
+		testState *framework.CycleState
+	)
+
+	newQuotaPod := func(name, namespace string, number int64) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				UID:       uuid.NewUUID(),
+			},
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{{
+					Name: "default",
+					Resources: v1.ResourceRequirements{
+						Limits: v1.ResourceList{
+							util.VGPUNumberResourceName: *resource.NewQuantity(number, resource.DecimalSI),
+						},
+					},
+				}},
+			},
+		}
+	}
+	addRunningPod := func(name, namespace string, borrowed bool) *v1.Pod {
+		pod := newQuotaPod(name, namespace, 1)
+		pod.Spec.NodeName = "test-node"
+		pod.Annotations = map[string]string{util.PodVGPURealAllocAnnotation: "default[0_GPU-0_0_1024]"}
+		if borrowed {
+			pod.Labels = map[string]string{BorrowedPodLabel: "true"}
+		}
+		Expect(indexer.Add(pod)).To(Succeed())
+		return pod
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		testState = framework.NewCycleState()
+		indexer = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		plugin = &VGPUSchedulerPlugin{
+			handle:        &eventHandleStub{recorder: events.NewFakeRecorder(10)},
+			podlister:     listersv1.NewPodLister(indexer),
+			cache:         newDeviceCache(),
+			elasticQuotas: newElasticQuotaManager(),
+		}
+		err := plugin.elasticQuotas.update(&v1.ConfigMap{Data: map[string]string{elasticQuotasConfigMapKey: `
+- name: team-a
+  namespaces: [team-a]
+  min: {number: 2}
+  max: {number: 4}
+- name: team-b
+  namespaces: [team-b]
+  min: {number: 2}
+  max: {number: 4}
+`}})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should keep the previous quotas when the ConfigMap is invalid", func() {
+		err := plugin.elasticQuotas.update(&v1.ConfigMap{Data: map[string]string{elasticQuotasConfigMapKey: `
+- name: team-a
+  namespaces: [team-a]
+  min: {number: 4}
+  max: {number: 2}
+`}})
+		Expect(err).To(HaveOccurred())
+		Expect(plugin.elasticQuotas.quotaOf("team-b")).NotTo(BeNil())
+	})
+
+	It("should ignore pods without elastic quota", func() {
+		_, status := plugin.PreFilter(ctx, testState, newQuotaPod("test-pod", "team-c", 8))
+		Expect(status.IsSuccess()).To(BeTrue())
+		Expect(getElasticQuotaState(testState)).To(BeNil())
+	})
+
+	It("should reject pods exceeding the max of their quota", func() {
+		_, status := plugin.PreFilter(ctx, testState, newQuotaPod("test-pod", "team-a", 5))
+		Expect(status.Code()).To(Equal(framework.UnschedulableAndUnresolvable))
+	})
+
+	It("should let pods borrow idle guaranteed resources", func() {
+		addRunningPod("running-a", "team-a", false)
+		_, status := plugin.PreFilter(ctx, testState, newQuotaPod("test-pod", "team-a", 2))
+		Expect(status.IsSuccess()).To(BeTrue())
+		Expect(getElasticQuotaState(testState).borrowing).To(BeTrue())
+	})
+
+	It("should not let pods borrow resources used by other quotas", func() {
+		addRunningPod("running-a", "team-a", false)
+		addRunningPod("running-b0", "team-b", false)
+		addRunningPod("running-b1", "team-b", false)
+		_, status := plugin.PreFilter(ctx, testState, newQuotaPod("test-pod", "team-a", 2))
+		Expect(status.Code()).To(Equal(framework.Unschedulable))
+	})
+
+	It("should reclaim the borrowed pods of overused quotas", func() {
+		ownPod := addRunningPod("running-a0", "team-a", false)
+		addRunningPod("running-a1", "team-a", false)
+		borrowedPod := addRunningPod("running-a2", "team-a", true)
+		_, status := plugin.PreFilter(ctx, testState, newQuotaPod("test-pod", "team-b", 1))
+		Expect(status.IsSuccess()).To(BeTrue())
+		quotaState := getElasticQuotaState(testState)
+		Expect(quotaState.borrowing).To(BeFalse())
+		Expect(quotaState.overused.Has("team-a")).To(BeTrue())
+
+		Expect(plugin.isElasticQuotaVictim(quotaState, borrowedPod, false)).To(BeTrue())
+		Expect(plugin.isElasticQuotaVictim(quotaState, ownPod, false)).To(BeFalse())
+	})
+
+	It("should only let borrowing pods preempt their own quota", func() {
+		quotaState := &elasticQuotaState{quota: "team-a", borrowing: true}
+		Expect(plugin.isElasticQuotaVictim(quotaState, newQuotaPod("pod", "team-a", 1), true)).To(BeTrue())
+		Expect(plugin.isElasticQuotaVictim(quotaState, newQuotaPod("pod", "team-b", 1), true)).To(BeFalse())
+	})
+
+	Context("when preempting", func() {
+		var (
+			nodeInfo *framework.NodeInfo
+			dp       *devicePreemption
+		)
+
+		// newNode returns a node with the given number of GPUs, each holding a single vGPU.
+		newNode := func(gpus int) *v1.Node {
+			heartbeat, _ := metav1.NowMicro().MarshalText()
+			var register []string
+			for i := 0; i < gpus; i++ {
+				register = append(register, fmt.Sprintf(`{"id":%d,"uuid":"GPU-%d","core":100,"memory":10240,"number":1,"healthy":true}`, i, i))
+			}
+			return &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-node",
+					UID:  uuid.NewUUID(),
+					Annotations: map[string]string{
+						util.NodeDeviceHeartbeatAnnotation: string(heartbeat),
+						util.NodeConfigInfoAnnotation:      `{"deviceSplit":1,"coresScaling":1,"memoryFactor":1,"memoryScaling":1}`,
+						util.NodeDeviceRegisterAnnotation:  "[" + strings.Join(register, ",") + "]",
+					},
+				},
+				Status: v1.NodeStatus{
+					Allocatable: v1.ResourceList{util.VGPUNumberResourceName: *resource.NewQuantity(int64(gpus), resource.DecimalSI)},
+				},
+			}
+		}
+		setupNode := func(gpus int, podNamespaces ...string) {
+			node := newNode(gpus)
+			nodeInfo = framework.NewNodeInfo()
+			nodeInfo.SetNode(node)
+			plugin.cache.updateNode(node)
+			for i, namespace := range podNamespaces {
+				pod := newQuotaPod(fmt.Sprintf("running-%d", i), namespace, 1)
+				pod.Spec.NodeName = node.Name
+				pod.Spec.Priority = ptr.To[int32](0)
+				pod.Annotations = map[string]string{util.PodVGPURealAllocAnnotation: fmt.Sprintf("default[%d_GPU-%d_100_10240]", i, i)}
+				Expect(indexer.Add(pod)).To(Succeed())
+				nodeInfo.AddPod(pod)
+				plugin.cache.updatePod(pod)
+			}
+		}
+		newPreemptor := func(namespace string, number int64) *v1.Pod {
+			pod := newQuotaPod("test-pod", namespace, number)
+			pod.Spec.Priority = ptr.To[int32](100)
+			return pod
+		}
+
+		BeforeEach(func() {
+			plugin.handle = &preemptionHandleStub{Handle: plugin.handle, plugin: plugin}
+			dp = &devicePreemption{plugin: plugin}
+		})
+
+		It("should not preempt other quotas for pods that cannot borrow", func() {
+			setupNode(3, "team-a", "team-b", "team-b")
+			pod := newPreemptor("team-a", 2)
+			_, status := plugin.PreFilter(ctx, testState, pod)
+			Expect(status.Code()).To(Equal(framework.Unschedulable))
+			Expect(getElasticQuotaState(testState).borrowing).To(BeTrue())
+
+			victims, _, status := dp.SelectVictimsOnNode(ctx, testState.Clone(), pod, nodeInfo.Snapshot(), nil)
+			Expect(status.Code()).To(Equal(framework.UnschedulableAndUnresolvable))
+			Expect(victims).To(BeEmpty())
+		})
+
+		It("should only select victims leaving the pod within its quota", func() {
+			setupNode(4, "team-a", "team-b", "team-b")
+			pod := newPreemptor("team-a", 2)
+			_, status := plugin.PreFilter(ctx, testState, pod)
+			Expect(status.Code()).To(Equal(framework.Unschedulable))
+
+			victims, _, status := dp.SelectVictimsOnNode(ctx, testState.Clone(), pod, nodeInfo.Snapshot(), nil)
+			Expect(status.IsSuccess()).To(BeTrue())
+			Expect(victims).To(HaveLen(1))
+			Expect(victims[0].Name).To(Equal("running-0"))
+		})
+
+		It("should not select victims leaving the pod over its quota", func() {
+			setupNode(5, "team-a", "team-b", "team-b")
+			pod := newPreemptor("team-a", 3)
+			_, status := plugin.PreFilter(ctx, testState, pod)
+			Expect(status.Code()).To(Equal(framework.Unschedulable))
+
+			victims, _, status := dp.SelectVictimsOnNode(ctx, testState.Clone(), pod, nodeInfo.Snapshot(), nil)
+			Expect(status.Code()).To(Equal(framework.UnschedulableAndUnresolvable))
+			Expect(victims).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,102 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/device"
+	"github.com/coldzerofear/vgpu-manager/pkg/scheduler/filter"
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	schedutil "k8s.io/kubernetes/pkg/scheduler/util"
+)
+
+var _ framework.EnqueueExtensions = &VGPUSchedulerPlugin{}
+
+// nodeDeviceAnnotations are the node annotations describing the GPU devices,
+// the node heartbeat annotation is not among them as it changes periodically.
+var nodeDeviceAnnotations = []string{
+	util.NodeDeviceRegisterAnnotation,
+	util.NodeDeviceTopologyAnnotation,
+	util.NodeConfigInfoAnnotation,
+	NodeQuarantinedGPUsAnnotation,
+}
+
+// EventsToRegister returns the events that may make a vGPU pod rejected by this plugin schedulable.
+func (p *VGPUSchedulerPlugin) EventsToRegister(_ context.Context) ([]framework.ClusterEventWithHint, error) {
+	return []framework.ClusterEventWithHint{
+		{Event: framework.ClusterEvent{Resource: framework.Pod, ActionType: framework.Delete}, QueueingHintFn: p.isSchedulableAfterPodDeleted},
+		{Event: framework.ClusterEvent{Resource: framework.Pod, ActionType: framework.Update}, QueueingHintFn: p.isSchedulableAfterPodUpdated},
+		{Event: framework.ClusterEvent{Resource: framework.Node, ActionType: framework.Add | framework.UpdateNodeAllocatable | framework.UpdateNodeAnnotation}, QueueingHintFn: p.isSchedulableAfterNodeChanged},
+	}, nil
+}
+
+// isSchedulableAfterPodDeleted requeues the pod when a pod occupying GPU devices is deleted.
+func (p *VGPUSchedulerPlugin) isSchedulableAfterPodDeleted(logger klog.Logger, pod *v1.Pod, oldObj, _ interface{}) (framework.QueueingHint, error) {
+	deletedPod, _, err := schedutil.As[*v1.Pod](oldObj, nil)
+	if err != nil {
+		return framework.Queue, err
+	}
+	if accountedNodeName(deletedPod) == "" {
+		logger.V(5).Info("deleted pod did not occupy any GPU device", "pod", klog.KObj(pod), "deletedPod", klog.KObj(deletedPod))
+		return framework.QueueSkip, nil
+	}
+	logger.V(5).Info("deleted pod released GPU devices", "pod", klog.KObj(pod), "deletedPod", klog.KObj(deletedPod))
+	return framework.Queue, nil
+}
+
+// isSchedulableAfterPodUpdated requeues the pod when a pod occupying GPU devices finishes,
+// or when the vGPU requests of the pod itself are updated.
+func (p *VGPUSchedulerPlugin) isSchedulableAfterPodUpdated(logger klog.Logger, pod *v1.Pod, oldObj, newObj interface{}) (framework.QueueingHint, error) {
+	oldPod, newPod, err := schedutil.As[*v1.Pod](oldObj, newObj)
+	if err != nil {
+		return framework.Queue, err
+	}
+	if newPod.UID == pod.UID {
+		if !equality.Semantic.DeepEqual(oldPod.Annotations, newPod.Annotations) ||
+			!equality.Semantic.DeepEqual(oldPod.Spec.Containers, newPod.Spec.Containers) {
+			logger.V(5).Info("vGPU requests of the pod were updated", "pod", klog.KObj(pod))
+			return framework.Queue, nil
+		}
+		return framework.QueueSkip, nil
+	}
+	if accountedNodeName(oldPod) != "" && accountedNodeName(newPod) == "" {
+		logger.V(5).Info("updated pod released GPU devices", "pod", klog.KObj(pod), "updatedPod", klog.KObj(newPod))
+		return framework.Queue, nil
+	}
+	return framework.QueueSkip, nil
+}
+
+// isSchedulableAfterNodeChanged requeues the pod when a GPU node joins, gets more vGPU
+// allocatable, changes its GPU devices, or becomes available to allocate devices again.
+func (p *VGPUSchedulerPlugin) isSchedulableAfterNodeChanged(logger klog.Logger, pod *v1.Pod, oldObj, newObj interface{}) (framework.QueueingHint, error) {
+	oldNode, newNode, err := schedutil.As[*v1.Node](oldObj, newObj)
+	if err != nil {
+		return framework.Queue, err
+	}
+	if !util.IsVGPUEnabledNode(newNode) {
+		return framework.QueueSkip, nil
+	}
+	if oldNode == nil {
+		logger.V(5).Info("new GPU node was added", "pod", klog.KObj(pod), "node", klog.KObj(newNode))
+		return framework.Queue, nil
+	}
+	if util.GetAllocatableOfNode(newNode, util.VGPUNumberResourceName) > util.GetAllocatableOfNode(oldNode, util.VGPUNumberResourceName) {
+		logger.V(5).Info("vGPU allocatable of the node increased", "pod", klog.KObj(pod), "node", klog.KObj(newNode))
+		return framework.Queue, nil
+	}
+	for _, annotation := range nodeDeviceAnnotations {
+		if oldNode.Annotations[annotation] != newNode.Annotations[annotation] {
+			logger.V(5).Info("GPU devices of the node changed", "pod", klog.KObj(pod), "node", klog.KObj(newNode), "annotation", annotation)
+			return framework.Queue, nil
+		}
+	}
+	noCheck := func(*device.NodeConfigInfo) error { return nil }
+	if filter.CheckNode(oldNode, noCheck) != nil && filter.CheckNode(newNode, noCheck) == nil {
+		logger.V(5).Info("node became available to allocate GPU devices", "pod", klog.KObj(pod), "node", klog.KObj(newNode))
+		return framework.Queue, nil
+	}
+	return framework.QueueSkip, nil
+}
@@ -0,0 +1,145 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+var _ = Describe("VGPUSchedulerPlugin EnqueueExtensions", func() {
+	var (
+		plugin     *VGPUSchedulerPlugin
+		logger     klog.Logger
+		pendingPod *v1.Pod
+		gpuPod     *v1.Pod
+		gpuNode    *v1.Node
+	)
+
+	newVGPUPod := func(name, nodeName string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				UID:       uuid.NewUUID(),
+			},
+			Spec: v1.PodSpec{
+				NodeName: nodeName,
+				Containers: []v1.Container{{
+					Name: "default",
+					Resources: v1.ResourceRequirements{
+						Limits: v1.ResourceList{
+							util.VGPUNumberResourceName: resource.MustParse("1"),
+						},
+					},
+				}},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		plugin = &VGPUSchedulerPlugin{}
+		logger = klog.Background()
+		pendingPod = newVGPUPod("pending-pod", "")
+		gpuPod = newVGPUPod("gpu-pod", "test-node")
+		gpuNode = &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-node",
+				Annotations: map[string]string{
+					util.NodeDeviceRegisterAnnotation: `[{"id":0,"uuid":"GPU-0","core":100,"memory":10240,"number":10,"healthy":true}]`,
+				},
+			},
+			Status: v1.NodeStatus{
+				Allocatable: v1.ResourceList{
+					util.VGPUNumberResourceName: resource.MustParse("10"),
+				},
+			},
+		}
+	})
+
+	It("should register pod and node events", func() {
+		events, err := plugin.EventsToRegister(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(events).To(HaveLen(3))
+	})
+
+	It("should requeue only when a pod occupying GPU devices is deleted", func() {
+		hint, err := plugin.isSchedulableAfterPodDeleted(logger, pendingPod, gpuPod, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hint).To(Equal(framework.Queue))
+
+		cpuPod := gpuPod.DeepCopy()
+		cpuPod.Spec.Containers[0].Resources.Limits = nil
+		hint, err = plugin.isSchedulableAfterPodDeleted(logger, pendingPod, cpuPod, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hint).To(Equal(framework.QueueSkip))
+	})
+
+	It("should requeue when a pod occupying GPU devices finishes", func() {
+		finishedPod := gpuPod.DeepCopy()
+		finishedPod.Status.Phase = v1.PodSucceeded
+		hint, err := plugin.isSchedulableAfterPodUpdated(logger, pendingPod, gpuPod, finishedPod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hint).To(Equal(framework.Queue))
+
+		runningPod := gpuPod.DeepCopy()
+		runningPod.Status.Phase = v1.PodRunning
+		hint, err = plugin.isSchedulableAfterPodUpdated(logger, pendingPod, gpuPod, runningPod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hint).To(Equal(framework.QueueSkip))
+	})
+
+	It("should requeue when the vGPU requests of the pod itself are updated", func() {
+		updatedPod := pendingPod.DeepCopy()
+		updatedPod.Annotations = map[string]string{util.MemorySchedulerPolicyAnnotation: "virtual"}
+		hint, err := plugin.isSchedulableAfterPodUpdated(logger, pendingPod, pendingPod, updatedPod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hint).To(Equal(framework.Queue))
+
+		updatedPod = pendingPod.DeepCopy()
+		updatedPod.Labels = map[string]string{"foo": "bar"}
+		hint, err = plugin.isSchedulableAfterPodUpdated(logger, pendingPod, pendingPod, updatedPod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hint).To(Equal(framework.QueueSkip))
+	})
+
+	It("should requeue when a GPU node joins", func() {
+		hint, err := plugin.isSchedulableAfterNodeChanged(logger, pendingPod, nil, gpuNode)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hint).To(Equal(framework.Queue))
+
+		cpuNode := gpuNode.DeepCopy()
+		cpuNode.Status.Allocatable = nil
+		hint, err = plugin.isSchedulableAfterNodeChanged(logger, pendingPod, nil, cpuNode)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hint).To(Equal(framework.QueueSkip))
+	})
+
+	It("should requeue only when GPU resources of the node change", func() {
+		heartbeatNode := gpuNode.DeepCopy()
+		heartbeatNode.Annotations[util.NodeDeviceHeartbeatAnnotation] = "heartbeat"
+		hint, err := plugin.isSchedulableAfterNodeChanged(logger, pendingPod, gpuNode, heartbeatNode)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hint).To(Equal(framework.QueueSkip))
+
+		registeredNode := gpuNode.DeepCopy()
+		registeredNode.Annotations[util.NodeDeviceRegisterAnnotation] = `[]`
+		hint, err = plugin.isSchedulableAfterNodeChanged(logger, pendingPod, gpuNode, registeredNode)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hint).To(Equal(framework.Queue))
+
+		scaledNode := gpuNode.DeepCopy()
+		scaledNode.Status.Allocatable[util.VGPUNumberResourceName] = resource.MustParse("20")
+		hint, err = plugin.isSchedulableAfterNodeChanged(logger, pendingPod, gpuNode, scaledNode)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hint).To(Equal(framework.Queue))
+	})
+
+})
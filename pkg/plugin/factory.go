@@ -2,55 +2,105 @@ package plugin
 
 import (
 	"context"
-	"sync"
+	"fmt"
+	"strings"
+	"time"
 
-	"github.com/coldzerofear/vgpu-manager/cmd/scheduler/options"
+	pluginconfig "github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/apis/config"
+	"github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/apis/config/validation"
+	"github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/metrics"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	v1 "k8s.io/client-go/listers/core/v1"
-	"k8s.io/component-base/featuregate"
-	baseversion "k8s.io/component-base/version"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/preemption"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
 )
 
 const Name = "VGPUSchedulerPlugin"
 
 func New(ctx context.Context, obj runtime.Object, handle framework.Handle) (framework.Plugin, error) {
-	featureGate := featuregate.NewFeatureGate()
-	err := featureGate.Add(map[featuregate.Feature]featuregate.FeatureSpec{
-		options.GPUTopology: {Default: true, PreRelease: featuregate.Alpha},
-	})
+	args, err := getArgs(obj)
 	if err != nil {
 		return nil, err
 	}
-	err = featuregate.DefaultComponentGlobalsRegistry.Register(
-		options.Component, baseversion.DefaultBuildEffectiveVersion(), featureGate)
+	if err = setupFeatureGates(args.FeatureGates); err != nil {
+		return nil, fmt.Errorf("invalid %s args: featureGates: %w", Name, err)
+	}
+	metrics.Register()
+	shared, err := getOrCreateSharedState(ctx, handle, args)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid %s args: %w", Name, err)
+	}
+	quotas, err := newVGPUQuotas(args.Quotas)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s args: %w", Name, err)
 	}
-	podLister := handle.SharedInformerFactory().Core().V1().Pods().Lister()
-	return &VGPUSchedulerPlugin{
-		handle:    handle,
-		podlister: podLister,
-	}, nil
+	if shared.elasticQuotas != nil {
+		if err = shared.elasticQuotas.waitForSync(ctx, handle.SharedInformerFactory()); err != nil {
+			return nil, err
+		}
+	}
+	plugin := &VGPUSchedulerPlugin{
+		handle:              handle,
+		podlister:           handle.SharedInformerFactory().Core().V1().Pods().Lister(),
+		cache:               shared.cache,
+		throttle:            shared.throttle,
+		health:              shared.health,
+		podGroupWaitTimeout: args.PodGroupWaitTimeout.Duration,
+		quotas:              quotas,
+		elasticQuotas:       shared.elasticQuotas,
+		nodeScorers:         newNodeScorers(args),
+		bindEnabled:         args.EnableBind,
+		defaultPolicies: schedulingPolicies{
+			nodePolicy:   strings.ToLower(*args.DefaultNodePolicy),
+			devicePolicy: strings.ToLower(*args.DefaultDevicePolicy),
+		},
+		topologyBonusPercent:      *args.TopologyBonusPercent,
+		gpuFlappingPenaltyPercent: *args.GPUFlappingPenaltyPercent,
+	}
+	plugin.evaluator = preemption.NewEvaluator(Name, handle, &devicePreemption{plugin: plugin}, false)
+	return plugin, nil
 }
 
+// getArgs decodes, defaults and validates the plugin args.
+func getArgs(obj runtime.Object) (*pluginconfig.VGPUSchedulerPluginArgs, error) {
+	args := &pluginconfig.VGPUSchedulerPluginArgs{}
+	if err := frameworkruntime.DecodeInto(obj, args); err != nil {
+		return nil, fmt.Errorf("decoding %s args: %w", Name, err)
+	}
+	pluginconfig.SetDefaults(args)
+	if err := validation.ValidateVGPUSchedulerPluginArgs(field.NewPath("args"), args, registeredNodePolicies(), devicePolicies); err != nil {
+		return nil, fmt.Errorf("invalid %s args: %w", Name, err)
+	}
+	return args, nil
+}
+
+// VGPUSchedulerPlugin is instantiated once per scheduler profile enabling it. The device
+// cache, binding throttle and GPU health are shared by the instances, the scheduling
+// policies, scores and quotas are configured per instance.
 type VGPUSchedulerPlugin struct {
-	mu        sync.Mutex
-	timestamp int64
 	handle    framework.Handle
 	podlister v1.PodLister
+	cache     *deviceCache
+	throttle  *bindThrottle
+	health    *gpuHealthTracker
+	evaluator *preemption.Evaluator
+
+	nodeScorers               map[string]NodeScorer
+	bindEnabled               bool
+	defaultPolicies           schedulingPolicies
+	topologyBonusPercent      int64
+	gpuFlappingPenaltyPercent int64
+	podGroupWaitTimeout       time.Duration
+	quotas                    []*vgpuQuota
+	elasticQuotas             *elasticQuotaManager
 }
 
 func (p *VGPUSchedulerPlugin) Name() string {
 	return Name
 }
 
-type resourceNumber int
-
-func (r resourceNumber) Clone() framework.StateData {
-	return r
-}
-
 type preAllocateDevice string
 
 func (r preAllocateDevice) Clone() framework.StateData {
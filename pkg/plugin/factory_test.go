@@ -0,0 +1,77 @@
+package plugin
+
+import (
+	"time"
+
+	pluginconfig "github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/apis/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var _ = Describe("VGPUSchedulerPlugin Args", func() {
+	Context("when no plugin args are configured", func() {
+		It("should return the default args", func() {
+			args, err := getArgs(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*args.DefaultNodePolicy).To(Equal(pluginconfig.DefaultNodePolicy))
+			Expect(*args.TopologyBonusPercent).To(Equal(pluginconfig.DefaultTopologyBonusPercent))
+			Expect(args.BindThrottleInterval.Duration).To(Equal(pluginconfig.DefaultBindThrottleInterval))
+			Expect(args.PodGroupWaitTimeout.Duration).To(Equal(pluginconfig.DefaultPodGroupWaitTimeout))
+			Expect(args.GPUFlappingWindow.Duration).To(Equal(pluginconfig.DefaultGPUFlappingWindow))
+			Expect(*args.GPUFlappingPenaltyPercent).To(Equal(pluginconfig.DefaultGPUFlappingPenaltyPercent))
+			Expect(args.AllocationTimeout.Duration).To(Equal(pluginconfig.DefaultAllocationTimeout))
+			Expect(*args.NodeScoringWeights).To(Equal(pluginconfig.NodeScoringWeights{Cores: 1, Memory: 1}))
+		})
+	})
+
+	Context("when plugin args are configured", func() {
+		It("should decode and keep the configured values", func() {
+			obj := &runtime.Unknown{
+				Raw: []byte(`{"defaultNodePolicy":"binpack","topologyBonusPercent":20,` +
+					`"bindThrottleInterval":"50ms","featureGates":{"GPUTopology":false}}`),
+				ContentType: runtime.ContentTypeJSON,
+			}
+			args, err := getArgs(obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*args.DefaultNodePolicy).To(Equal("binpack"))
+			Expect(*args.TopologyBonusPercent).To(Equal(int64(20)))
+			Expect(args.BindThrottleInterval.Duration).To(Equal(50 * time.Millisecond))
+			Expect(args.FeatureGates).To(HaveKeyWithValue("GPUTopology", false))
+		})
+	})
+
+	Context("when plugin args are malformed", func() {
+		It("should return a decoding error", func() {
+			obj := &runtime.Unknown{Raw: []byte(`{"topologyBonusPercent":"ten"}`)}
+			_, err := getArgs(obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("decoding VGPUSchedulerPlugin args"))
+		})
+		It("should reject an unsupported node policy", func() {
+			obj := &runtime.Unknown{Raw: []byte(`{"defaultNodePolicy":"random"}`)}
+			_, err := getArgs(obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("args.defaultNodePolicy"))
+		})
+		It("should reject node scoring weights without a positive weight", func() {
+			obj := &runtime.Unknown{Raw: []byte(`{"nodeScoringWeights":{"cores":0,"memory":0}}`)}
+			_, err := getArgs(obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("args.nodeScoringWeights"))
+		})
+		It("should reject an out of range topology bonus", func() {
+			obj := &runtime.Unknown{Raw: []byte(`{"topologyBonusPercent":120}`)}
+			_, err := getArgs(obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("args.topologyBonusPercent"))
+		})
+		It("should reject invalid quotas", func() {
+			obj := &runtime.Unknown{Raw: []byte(`{"quotas":[{"number":-1}]}`)}
+			_, err := getArgs(obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("args.quotas[0].name"))
+			Expect(err.Error()).To(ContainSubstring("args.quotas[0].number"))
+		})
+	})
+})
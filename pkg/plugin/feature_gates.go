@@ -0,0 +1,68 @@
+package plugin
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/coldzerofear/vgpu-manager/cmd/scheduler/options"
+	"k8s.io/component-base/featuregate"
+	baseversion "k8s.io/component-base/version"
+)
+
+// pluginFeatures are the features of the plugin that can be toggled by the featureGates plugin args.
+var pluginFeatures = map[featuregate.Feature]featuregate.FeatureSpec{
+	options.GPUTopology: {Default: true, PreRelease: featuregate.Alpha},
+}
+
+var (
+	featureGatesMutex sync.Mutex
+	// featureGates is the feature gate registered for the process by the first plugin instance.
+	featureGates featuregate.FeatureGate
+)
+
+// newPluginFeatureGate returns a feature gate of the plugin features with the settings applied.
+func newPluginFeatureGate(settings map[string]bool) (featuregate.MutableVersionedFeatureGate, error) {
+	featureGate := featuregate.NewFeatureGate()
+	if err := featureGate.Add(pluginFeatures); err != nil {
+		return nil, err
+	}
+	if err := featureGate.SetFromMap(settings); err != nil {
+		return nil, err
+	}
+	return featureGate, nil
+}
+
+// setupFeatureGates registers the plugin feature gate in the component globals registry once
+// per process, where vgpu-manager reads it. The feature gate is shared by all the plugin
+// instances (e.g. one per scheduler profile), which must therefore configure the same features.
+func setupFeatureGates(settings map[string]bool) error {
+	featureGate, err := newPluginFeatureGate(settings)
+	if err != nil {
+		return err
+	}
+	featureGatesMutex.Lock()
+	defer featureGatesMutex.Unlock()
+	if featureGates == nil {
+		err = featuregate.DefaultComponentGlobalsRegistry.Register(
+			options.Component, baseversion.DefaultBuildEffectiveVersion(), featureGate)
+		if err != nil {
+			return err
+		}
+		featureGates = featureGate
+		return nil
+	}
+	for feature := range pluginFeatures {
+		if featureGate.Enabled(feature) != featureGates.Enabled(feature) {
+			return fmt.Errorf("feature %s is %s by another %s instance, all instances must configure the same features",
+				feature, enabledString(featureGates.Enabled(feature)), Name)
+		}
+	}
+	return nil
+}
+
+func enabledString(enabled bool) string {
+	if enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
@@ -0,0 +1,28 @@
+package plugin
+
+import (
+	"github.com/coldzerofear/vgpu-manager/cmd/scheduler/options"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/component-base/featuregate"
+)
+
+var _ = Describe("VGPUSchedulerPlugin feature gates", func() {
+	It("should register the feature gate once and share it across instances", func() {
+		Expect(setupFeatureGates(map[string]bool{string(options.GPUTopology): true})).To(Succeed())
+		Expect(setupFeatureGates(nil)).To(Succeed())
+		featureGate := featuregate.DefaultComponentGlobalsRegistry.FeatureGateFor(options.Component)
+		Expect(featureGate).NotTo(BeNil())
+		Expect(featureGate.Enabled(options.GPUTopology)).To(BeTrue())
+	})
+
+	It("should reject settings conflicting with another instance", func() {
+		Expect(setupFeatureGates(nil)).To(Succeed())
+		err := setupFeatureGates(map[string]bool{string(options.GPUTopology): false})
+		Expect(err).To(MatchError(ContainSubstring("feature GPUTopology is enabled by another VGPUSchedulerPlugin instance")))
+	})
+
+	It("should reject unknown features", func() {
+		Expect(setupFeatureGates(map[string]bool{"Unknown": true})).NotTo(Succeed())
+	})
+})
@@ -3,13 +3,14 @@ package plugin
 import (
 	"context"
 	"fmt"
+	"time"
+
+	"github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/metrics"
 
 	"github.com/coldzerofear/vgpu-manager/pkg/device"
-	"github.com/coldzerofear/vgpu-manager/pkg/device/allocator"
 	"github.com/coldzerofear/vgpu-manager/pkg/scheduler/filter"
 	"github.com/coldzerofear/vgpu-manager/pkg/util"
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
@@ -19,27 +20,31 @@ var _ framework.FilterPlugin = &VGPUSchedulerPlugin{}
 func (p *VGPUSchedulerPlugin) Filter(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeInfo *framework.NodeInfo) (status *framework.Status) {
 	defer func() {
 		if !status.IsSuccess() {
+			p.recordFilterFailure(state, nodeInfo.GetName(), status)
 			p.deleteDevNodeInfo(state, nodeInfo)
 			state.Delete(p.preAllocateDeviceKey(nodeInfo.GetName()))
 		}
 	}()
 	logger := klog.FromContext(ctx)
+	request := p.getPodRequest(state, pod)
 	// GPUs not provided on node, Unschedulable
 	nodeVGPUNumber := util.GetAllocatableOfNode(nodeInfo.Node(), util.VGPUNumberResourceName)
 	if nodeVGPUNumber == 0 {
 		logger.Info("node does not have GPU", "node", nodeInfo.GetName())
+		metrics.FilterRejections.WithLabelValues(metrics.RejectReasonNoGPU).Inc()
 		return framework.NewStatus(framework.Unschedulable, "node does not have GPU")
 	}
 	// The requested GPU exceeds the number provided by the node, Unschedulable
-	if p.getTotalRequestVGPUByPod(state, pod) > nodeVGPUNumber {
+	if request.totalNumber > nodeVGPUNumber {
 		logger.Info("insufficient GPU on the node", "node", nodeInfo.GetName())
+		metrics.FilterRejections.WithLabelValues(metrics.RejectReasonInsufficientNumber).Inc()
 		return framework.NewStatus(framework.Unschedulable, "insufficient GPU on the node")
 	}
-	if status = p.nodeFilter(pod, nodeInfo); !status.IsSuccess() {
+	if status = p.nodeFilter(request, nodeInfo); !status.IsSuccess() {
 		logger.Error(fmt.Errorf("%s", status.String()), "node filter failed", "node", nodeInfo.GetName())
 		return status
 	}
-	if status = p.deviceFilter(state, pod, nodeInfo); !status.IsSuccess() {
+	if status = p.deviceFilter(state, pod, request, nodeInfo); !status.IsSuccess() {
 		logger.Error(fmt.Errorf("%s", status.String()), "device filter failed", "node", nodeInfo.GetName())
 		return status
 	}
@@ -47,28 +52,36 @@ func (p *VGPUSchedulerPlugin) Filter(ctx context.Context, state *framework.Cycle
 	return framework.NewStatus(framework.Success, "")
 }
 
+func devNodeInfoKey(nodeName string) framework.StateKey {
+	return framework.StateKey("DeviceNodeInfo_" + nodeName)
+}
+
 func (p *VGPUSchedulerPlugin) deleteDevNodeInfo(state *framework.CycleState, nodeInfo *framework.NodeInfo) {
-	state.Delete(framework.StateKey("DeviceNodeInfo_" + nodeInfo.GetName()))
+	state.Delete(devNodeInfoKey(nodeInfo.GetName()))
 }
 
 func (p *VGPUSchedulerPlugin) createDevNodeInfo(state *framework.CycleState, nodeInfo *framework.NodeInfo) (*device.NodeInfo, error) {
-	pods, err := p.podlister.List(labels.Everything())
-	if err != nil {
-		return nil, err
+	var (
+		devNodeInfo *device.NodeInfo
+		err         error
+	)
+	// Pods added or removed by the PreFilter extensions are only known to the cycle state.
+	if adjustment, ok := getPodsAdjustment(state, nodeInfo.GetName()); ok {
+		pods := adjustment.apply(p.cache.PodsOnNode(nodeInfo.GetName()))
+		devNodeInfo, err = device.NewNodeInfo(nodeInfo.Node(), pods)
+	} else {
+		devNodeInfo, err = p.cache.Snapshot(nodeInfo.Node())
 	}
-	devNodeInfo, err := device.NewNodeInfo(nodeInfo.Node(), pods)
 	if err != nil {
 		return nil, err
 	}
-	nodeInfoKey := framework.StateKey("DeviceNodeInfo_" + nodeInfo.GetName())
-	state.Write(nodeInfoKey, framework.StateData(devNodeInfo))
+	state.Write(devNodeInfoKey(nodeInfo.GetName()), framework.StateData(devNodeInfo))
 	return devNodeInfo, nil
 }
 
 func (p *VGPUSchedulerPlugin) getDevNodeInfo(state *framework.CycleState, nodeInfo *framework.NodeInfo) (*device.NodeInfo, error) {
 	var devNodeInfo *device.NodeInfo
-	nodeInfoKey := framework.StateKey("DeviceNodeInfo_" + nodeInfo.GetName())
-	if data, err := state.Read(nodeInfoKey); err != nil {
+	if data, err := state.Read(devNodeInfoKey(nodeInfo.GetName())); err != nil {
 		devNodeInfo, err = p.createDevNodeInfo(state, nodeInfo)
 		if err != nil {
 			return nil, err
@@ -79,14 +92,25 @@ func (p *VGPUSchedulerPlugin) getDevNodeInfo(state *framework.CycleState, nodeIn
 	return devNodeInfo, nil
 }
 
-func (p *VGPUSchedulerPlugin) deviceFilter(state *framework.CycleState, pod *v1.Pod, nodeInfo *framework.NodeInfo) (status *framework.Status) {
-	devNodeInfo, err := p.createDevNodeInfo(state, nodeInfo)
+func (p *VGPUSchedulerPlugin) deviceFilter(state *framework.CycleState, pod *v1.Pod, request *podRequest, nodeInfo *framework.NodeInfo) (status *framework.Status) {
+	devNodeInfo, err := p.getDevNodeInfo(state, nodeInfo)
 	if err != nil {
 		return framework.NewStatus(framework.Error, err.Error())
 	}
 	devNodeInfo = devNodeInfo.Clone().(*device.NodeInfo)
-	newPod, err := allocator.NewAllocator(devNodeInfo).Allocate(pod)
+	matched, err := maskDevices(devNodeInfo, request.deviceConstraints, quarantinedGPUs(nodeInfo.Node()))
+	if err != nil {
+		return framework.NewStatus(framework.Error, err.Error())
+	}
+	if matched == 0 {
+		metrics.FilterRejections.WithLabelValues(metrics.RejectReasonDeviceAllocation).Inc()
+		return framework.NewStatus(framework.Unschedulable, "no GPU on the node meets the device requirements of the pod")
+	}
+	startTime := time.Now()
+	newPod, err := allocateDevices(devNodeInfo, pod, request)
+	metrics.AllocationDuration.Observe(metrics.SinceInSeconds(startTime))
 	if err != nil {
+		metrics.FilterRejections.WithLabelValues(metrics.RejectReasonDeviceAllocation).Inc()
 		return framework.NewStatus(framework.Unschedulable, err.Error())
 	}
 	preAllocate := newPod.Annotations[util.PodVGPUPreAllocAnnotation]
@@ -95,9 +119,9 @@ func (p *VGPUSchedulerPlugin) deviceFilter(state *framework.CycleState, pod *v1.
 	return framework.NewStatus(framework.Success, "")
 }
 
-func (p *VGPUSchedulerPlugin) nodeFilter(pod *v1.Pod, nodeInfo *framework.NodeInfo) (status *framework.Status) {
-	memoryPolicyFunc := filter.GetMemoryPolicyFunc(pod)
-	if err := filter.CheckNode(nodeInfo.Node(), memoryPolicyFunc); err != nil {
+func (p *VGPUSchedulerPlugin) nodeFilter(request *podRequest, nodeInfo *framework.NodeInfo) (status *framework.Status) {
+	if err := filter.CheckNode(nodeInfo.Node(), request.memoryPolicyFunc); err != nil {
+		metrics.FilterRejections.WithLabelValues(metrics.RejectReasonNodeFilter).Inc()
 		return framework.NewStatus(framework.Unschedulable, err.Error())
 	}
 	return framework.NewStatus(framework.Success, "")
@@ -3,6 +3,7 @@ package plugin
 import (
 	"context"
 
+	"github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/metrics"
 	"github.com/coldzerofear/vgpu-manager/pkg/util"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -10,6 +11,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/component-base/metrics/testutil"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
@@ -100,6 +102,16 @@ var _ = Describe("VGPUSchedulerPlugin Filter", func() {
 			Expect(status.Code()).To(Equal(framework.Unschedulable))
 			Expect(status.Message()).To(ContainSubstring("node does not have GPU"))
 		})
+		It("should count the rejection", func() {
+			metrics.Register()
+			rejections := metrics.FilterRejections.WithLabelValues(metrics.RejectReasonNoGPU)
+			before, err := testutil.GetCounterMetricValue(rejections)
+			Expect(err).NotTo(HaveOccurred())
+			plugin.Filter(ctx, testState, testPod, nodeInfo)
+			after, err := testutil.GetCounterMetricValue(rejections)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(after - before).To(Equal(float64(1)))
+		})
 	})
 
 	Context("When the GPU of the node is insufficient", func() {
@@ -0,0 +1,68 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+)
+
+const (
+	allocationWatchdogLease = "vgpu-manager-scheduler-plugin-allocation-watchdog"
+	defaultLeaseNamespace   = "kube-system"
+	namespaceFile           = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+// leaseNamespace returns the namespace of the scheduler pod, where its leases are held.
+func leaseNamespace() string {
+	if data, err := os.ReadFile(namespaceFile); err == nil {
+		if namespace := strings.TrimSpace(string(data)); len(namespace) > 0 {
+			return namespace
+		}
+	}
+	return defaultLeaseNamespace
+}
+
+// runAsLeader runs the function while the replica holds the lease, and campaigns again when it is lost.
+func runAsLeader(ctx context.Context, kubeClient kubernetes.Interface, namespace, lease string, run func(ctx context.Context)) error {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	logger := klog.FromContext(ctx)
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Name: lease, Namespace: namespace},
+			Client:     kubeClient.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: hostname + "_" + string(uuid.NewUUID())},
+		},
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		ReleaseOnCancel: true,
+		Name:            lease,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: run,
+			OnStoppedLeading: func() {
+				logger.Info("Stopped leading", "lease", klog.KRef(namespace, lease))
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	go wait.UntilWithContext(ctx, elector.Run, retryPeriod)
+	return nil
+}
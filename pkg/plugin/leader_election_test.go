@@ -0,0 +1,40 @@
+package plugin
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("VGPUSchedulerPlugin leader election", func() {
+	It("should run on the leading replica only", func() {
+		fakeCli := fake.NewClientset()
+		var leaders atomic.Int32
+		campaign := func() (context.CancelFunc, *atomic.Bool) {
+			ctx, cancel := context.WithCancel(context.Background())
+			var leading atomic.Bool
+			Expect(runAsLeader(ctx, fakeCli, "kube-system", "test-lease", func(ctx context.Context) {
+				leaders.Add(1)
+				leading.Store(true)
+				<-ctx.Done()
+				leaders.Add(-1)
+			})).To(Succeed())
+			return cancel, &leading
+		}
+
+		cancelFirst, firstLeading := campaign()
+		Eventually(firstLeading.Load).Should(BeTrue())
+		cancelSecond, secondLeading := campaign()
+		defer cancelSecond()
+		Consistently(secondLeading.Load, 200*time.Millisecond).Should(BeFalse())
+
+		// The lease is released when the first replica stops.
+		cancelFirst()
+		Eventually(secondLeading.Load, 5*time.Second).Should(BeTrue())
+		Expect(leaders.Load()).To(Equal(int32(1)))
+	})
+})
@@ -0,0 +1,180 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	pluginconfig "github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/apis/config"
+	"github.com/coldzerofear/vgpu-manager/pkg/device"
+	"github.com/coldzerofear/vgpu-manager/pkg/device/allocator"
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// Node scheduling policies provided by the plugin in addition to none / binpack / spread.
+const (
+	// LeastFragmentationPolicy prefers the nodes whose free GPU memory is on partially used GPUs.
+	LeastFragmentationPolicy = "least-fragmentation"
+	// MemoryBinpackPolicy prefers the nodes with the most allocated GPU memory.
+	MemoryBinpackPolicy = "memory-binpack"
+	// PreferIdlePolicy prefers the nodes with the most idle GPUs, it is also a device policy.
+	PreferIdlePolicy = "prefer-idle"
+	// WeightedPolicy prefers the nodes with the most allocated GPU cores and memory, by nodeScoringWeights.
+	WeightedPolicy = "weighted"
+)
+
+// NodeScorer scores a node for a vGPU pod from the device view of the node.
+// The score must be in the range [framework.MinNodeScore, framework.MaxNodeScore].
+type NodeScorer interface {
+	Score(pod *v1.Pod, nodeInfo *device.NodeInfo) int64
+}
+
+// NodeScorerFunc is an adapter to use ordinary functions as node scorers.
+type NodeScorerFunc func(pod *v1.Pod, nodeInfo *device.NodeInfo) int64
+
+func (f NodeScorerFunc) Score(pod *v1.Pod, nodeInfo *device.NodeInfo) int64 {
+	return f(pod, nodeInfo)
+}
+
+// NodeScorerFactory builds the node scorer of a node scheduling policy from the plugin args.
+type NodeScorerFactory func(args *pluginconfig.VGPUSchedulerPluginArgs) NodeScorer
+
+var (
+	nodeScorersMutex    sync.RWMutex
+	nodeScorerFactories = map[string]NodeScorerFactory{
+		string(util.NonePolicy):    staticNodeScorer(NodeScorerFunc(scoreNeutral)),
+		string(util.BinpackPolicy): staticNodeScorer(NodeScorerFunc(scoreBinpack)),
+		string(util.SpreadPolicy):  staticNodeScorer(NodeScorerFunc(scoreSpread)),
+		LeastFragmentationPolicy:   staticNodeScorer(NodeScorerFunc(scoreLeastFragmentation)),
+		MemoryBinpackPolicy:        staticNodeScorer(NodeScorerFunc(scoreMemoryBinpack)),
+		PreferIdlePolicy:           staticNodeScorer(NodeScorerFunc(scorePreferIdle)),
+		WeightedPolicy:             newWeightedNodeScorer,
+	}
+)
+
+// RegisterNodeScorer registers the node scorer of a node scheduling policy. It must be called
+// before the plugin is created, e.g. from an init function of an out-of-tree build.
+func RegisterNodeScorer(policy string, factory NodeScorerFactory) error {
+	policy = strings.ToLower(policy)
+	if len(policy) == 0 || factory == nil {
+		return fmt.Errorf("node scorer must have a policy name and a factory")
+	}
+	nodeScorersMutex.Lock()
+	defer nodeScorersMutex.Unlock()
+	if _, ok := nodeScorerFactories[policy]; ok {
+		return fmt.Errorf("node scorer for policy %q is already registered", policy)
+	}
+	nodeScorerFactories[policy] = factory
+	return nil
+}
+
+func registeredNodePolicies() []string {
+	nodeScorersMutex.RLock()
+	defer nodeScorersMutex.RUnlock()
+	policies := make([]string, 0, len(nodeScorerFactories))
+	for policy := range nodeScorerFactories {
+		policies = append(policies, policy)
+	}
+	sort.Strings(policies)
+	return policies
+}
+
+func newNodeScorers(args *pluginconfig.VGPUSchedulerPluginArgs) map[string]NodeScorer {
+	nodeScorersMutex.RLock()
+	defer nodeScorersMutex.RUnlock()
+	scorers := make(map[string]NodeScorer, len(nodeScorerFactories))
+	for policy, factory := range nodeScorerFactories {
+		scorers[policy] = factory(args)
+	}
+	return scorers
+}
+
+func staticNodeScorer(scorer NodeScorer) NodeScorerFactory {
+	return func(*pluginconfig.VGPUSchedulerPluginArgs) NodeScorer {
+		return scorer
+	}
+}
+
+func scoreRatio(a, b int64) int64 {
+	if b <= 0 {
+		return framework.MinNodeScore
+	}
+	return framework.MaxNodeScore * a / b
+}
+
+// schedulableDevices returns the devices counted in the node resources.
+func schedulableDevices(nodeInfo *device.NodeInfo) []*device.Device {
+	var devices []*device.Device
+	for _, dev := range nodeInfo.GetDeviceMap() {
+		if !dev.IsMIG() && dev.Healthy() {
+			devices = append(devices, dev)
+		}
+	}
+	return devices
+}
+
+func scoreNeutral(*v1.Pod, *device.NodeInfo) int64 {
+	return neutralNodeScore
+}
+
+func scoreBinpack(_ *v1.Pod, nodeInfo *device.NodeInfo) int64 {
+	return int64(allocator.GetBinpackNodeScore(nodeInfo, float64(framework.MaxNodeScore)))
+}
+
+func scoreSpread(_ *v1.Pod, nodeInfo *device.NodeInfo) int64 {
+	return int64(allocator.GetSpreadNodeScore(nodeInfo, float64(framework.MaxNodeScore)))
+}
+
+func scoreMemoryBinpack(_ *v1.Pod, nodeInfo *device.NodeInfo) int64 {
+	total := int64(nodeInfo.GetTotalMemory())
+	return scoreRatio(total-int64(nodeInfo.GetAvailableMemory()), total)
+}
+
+// scoreLeastFragmentation scores the node by the share of its free GPU memory on partially used GPUs.
+func scoreLeastFragmentation(_ *v1.Pod, nodeInfo *device.NodeInfo) int64 {
+	var free, fragmented int64
+	for _, dev := range schedulableDevices(nodeInfo) {
+		if dev.AllocatableNumber() == 0 {
+			continue
+		}
+		free += int64(dev.AllocatableMemory())
+		if dev.AllocatableNumber() < dev.GetTotalNumber() {
+			fragmented += int64(dev.AllocatableMemory())
+		}
+	}
+	return scoreRatio(fragmented, free)
+}
+
+// scorePreferIdle scores the node by the share of its GPUs not used by any pod.
+func scorePreferIdle(_ *v1.Pod, nodeInfo *device.NodeInfo) int64 {
+	devices := schedulableDevices(nodeInfo)
+	var idle int64
+	for _, dev := range devices {
+		if dev.AllocatableNumber() == dev.GetTotalNumber() {
+			idle++
+		}
+	}
+	return scoreRatio(idle, int64(len(devices)))
+}
+
+type weightedNodeScorer struct {
+	coresWeight  int64
+	memoryWeight int64
+}
+
+func newWeightedNodeScorer(args *pluginconfig.VGPUSchedulerPluginArgs) NodeScorer {
+	return &weightedNodeScorer{
+		coresWeight:  args.NodeScoringWeights.Cores,
+		memoryWeight: args.NodeScoringWeights.Memory,
+	}
+}
+
+func (s *weightedNodeScorer) Score(_ *v1.Pod, nodeInfo *device.NodeInfo) int64 {
+	totalCores, totalMemory := int64(nodeInfo.GetTotalCores()), int64(nodeInfo.GetTotalMemory())
+	coresScore := scoreRatio(totalCores-int64(nodeInfo.GetAvailableCores()), totalCores)
+	memoryScore := scoreRatio(totalMemory-int64(nodeInfo.GetAvailableMemory()), totalMemory)
+	return (coresScore*s.coresWeight + memoryScore*s.memoryWeight) / (s.coresWeight + s.memoryWeight)
+}
@@ -0,0 +1,71 @@
+package plugin
+
+import (
+	pluginconfig "github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/apis/config"
+	"github.com/coldzerofear/vgpu-manager/pkg/device"
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+var _ = Describe("VGPUSchedulerPlugin node scorers", func() {
+	var (
+		args     *pluginconfig.VGPUSchedulerPluginArgs
+		nodeInfo *device.NodeInfo
+	)
+
+	BeforeEach(func() {
+		args = &pluginconfig.VGPUSchedulerPluginArgs{}
+		pluginconfig.SetDefaults(args)
+		heartbeat, _ := metav1.NowMicro().MarshalText()
+		node := &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-node",
+				Annotations: map[string]string{
+					util.NodeDeviceHeartbeatAnnotation: string(heartbeat),
+					util.NodeConfigInfoAnnotation:      `{"deviceSplit":10,"coresScaling":1,"memoryFactor":1,"memoryScaling":1}`,
+					util.NodeDeviceRegisterAnnotation:  `[{"id":0,"uuid":"GPU-0","core":100,"memory":10240,"number":10,"healthy":true},{"id":1,"uuid":"GPU-1","core":100,"memory":10240,"number":10,"healthy":true}]`,
+				},
+			},
+		}
+		var err error
+		nodeInfo, err = device.NewNodeInfo(node, nil)
+		Expect(err).NotTo(HaveOccurred())
+		// GPU-0 runs a vGPU with all of its cores and 2Gi of memory, GPU-1 is idle.
+		Expect(nodeInfo.AddUsedResources(0, 100, 2048)).To(Succeed())
+	})
+
+	score := func(policy string) int64 {
+		return newNodeScorers(args)[policy].Score(nil, nodeInfo)
+	}
+
+	It("should score the node with the built-in policies", func() {
+		Expect(score(string(util.NonePolicy))).To(Equal(int64(neutralNodeScore)))
+		Expect(score(MemoryBinpackPolicy)).To(Equal(int64(10)))
+		Expect(score(PreferIdlePolicy)).To(Equal(int64(50)))
+		Expect(score(LeastFragmentationPolicy)).To(Equal(int64(44)))
+		Expect(score(WeightedPolicy)).To(Equal(int64(30)))
+		args.NodeScoringWeights = &pluginconfig.NodeScoringWeights{Cores: 3, Memory: 1}
+		Expect(score(WeightedPolicy)).To(Equal(int64(40)))
+	})
+
+	It("should use the registered node scorers", func() {
+		Expect(RegisterNodeScorer("Constant", func(*pluginconfig.VGPUSchedulerPluginArgs) NodeScorer {
+			return NodeScorerFunc(func(*v1.Pod, *device.NodeInfo) int64 { return framework.MaxNodeScore })
+		})).To(Succeed())
+		Expect(RegisterNodeScorer("constant", nil)).NotTo(Succeed())
+		Expect(RegisterNodeScorer(string(util.BinpackPolicy), staticNodeScorer(NodeScorerFunc(scoreNeutral)))).NotTo(Succeed())
+		Expect(registeredNodePolicies()).To(ContainElement("constant"))
+
+		plugin := &VGPUSchedulerPlugin{nodeScorers: newNodeScorers(args)}
+		policy, scorer := plugin.nodeScorer(&podRequest{nodePolicy: "constant"})
+		Expect(policy).To(Equal("constant"))
+		Expect(scorer.Score(nil, nodeInfo)).To(Equal(framework.MaxNodeScore))
+		policy, scorer = plugin.nodeScorer(&podRequest{nodePolicy: "unknown"})
+		Expect(policy).To(Equal(string(util.NonePolicy)))
+		Expect(scorer.Score(nil, nodeInfo)).To(Equal(int64(neutralNodeScore)))
+	})
+})
@@ -0,0 +1,114 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+var _ framework.PermitPlugin = &VGPUSchedulerPlugin{}
+
+const (
+	// PodGroupLabel groups the pods that must be scheduled together.
+	PodGroupLabel = util.DomainPrefix + "/pod-group"
+	// PodGroupMinMemberAnnotation is the number of pods of the group that must get
+	// devices before any of them is bound.
+	PodGroupMinMemberAnnotation = util.DomainPrefix + "/pod-group-min-member"
+)
+
+// getPodGroup returns the pod group name and min member count of the pod,
+// the min member count is 0 when the pod does not belong to a pod group.
+func getPodGroup(pod *v1.Pod) (string, int, error) {
+	groupName, ok := util.HasLabel(pod, PodGroupLabel)
+	if !ok || len(groupName) == 0 {
+		return "", 0, nil
+	}
+	value, ok := util.HasAnnotation(pod, PodGroupMinMemberAnnotation)
+	if !ok {
+		return "", 0, fmt.Errorf("pod group %s without annotation %s", groupName, PodGroupMinMemberAnnotation)
+	}
+	minMember, err := strconv.Atoi(value)
+	if err != nil || minMember < 1 {
+		return "", 0, fmt.Errorf("pod group %s with invalid min member %q", groupName, value)
+	}
+	return groupName, minMember, nil
+}
+
+func inPodGroup(pod *v1.Pod, namespace, groupName string) bool {
+	return pod.Namespace == namespace && pod.Labels[PodGroupLabel] == groupName
+}
+
+// countBoundPodGroupMembers counts the pods of the group already bound to nodes.
+func (p *VGPUSchedulerPlugin) countBoundPodGroupMembers(namespace, groupName string) (int, error) {
+	selector := labels.SelectorFromSet(labels.Set{PodGroupLabel: groupName})
+	pods, err := p.podlister.Pods(namespace).List(selector)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, pod := range pods {
+		if len(pod.Spec.NodeName) > 0 && !util.PodIsTerminated(pod) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Permit holds the pods of a pod group, together with the devices reserved for them,
+// until the min member count of the group is reached, then allows the whole group.
+func (p *VGPUSchedulerPlugin) Permit(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) (*framework.Status, time.Duration) {
+	logger := klog.FromContext(ctx)
+	groupName, minMember, err := getPodGroup(pod)
+	if err != nil {
+		logger.Error(err, "getting pod group failed", "pod", klog.KObj(pod))
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, err.Error()), 0
+	}
+	if minMember <= 1 {
+		return framework.NewStatus(framework.Success, ""), 0
+	}
+	bound, err := p.countBoundPodGroupMembers(pod.Namespace, groupName)
+	if err != nil {
+		logger.Error(err, "listing pod group members failed", "pod", klog.KObj(pod), "podGroup", groupName)
+		return framework.NewStatus(framework.Error, err.Error()), 0
+	}
+	var waitingPods []framework.WaitingPod
+	p.handle.IterateOverWaitingPods(func(waitingPod framework.WaitingPod) {
+		if inPodGroup(waitingPod.GetPod(), pod.Namespace, groupName) && waitingPod.GetPod().UID != pod.UID {
+			waitingPods = append(waitingPods, waitingPod)
+		}
+	})
+	// The current pod is a member of the group as well.
+	if members := bound + len(waitingPods) + 1; members < minMember {
+		logger.V(4).Info("Waiting for pod group members", "pod", klog.KObj(pod), "podGroup", groupName,
+			"members", members, "minMember", minMember)
+		return framework.NewStatus(framework.Wait, ""), p.podGroupWaitTimeout
+	}
+	for _, waitingPod := range waitingPods {
+		waitingPod.Allow(Name)
+	}
+	logger.V(4).Info("Pod group reached min member", "pod", klog.KObj(pod), "podGroup", groupName, "minMember", minMember)
+	return framework.NewStatus(framework.Success, ""), 0
+}
+
+// rejectPodGroup rejects the other waiting pods of the pod group, so the devices reserved
+// for them are released instead of being held by an incomplete group.
+func (p *VGPUSchedulerPlugin) rejectPodGroup(ctx context.Context, pod *v1.Pod) {
+	groupName, minMember, _ := getPodGroup(pod)
+	if minMember <= 1 {
+		return
+	}
+	p.handle.IterateOverWaitingPods(func(waitingPod framework.WaitingPod) {
+		if inPodGroup(waitingPod.GetPod(), pod.Namespace, groupName) && waitingPod.GetPod().UID != pod.UID {
+			klog.FromContext(ctx).V(4).Info("Rejecting waiting pod of pod group", "pod", klog.KObj(waitingPod.GetPod()),
+				"podGroup", groupName, "rejectedBy", klog.KObj(pod))
+			waitingPod.Reject(Name, fmt.Sprintf("pod group %s was rejected", groupName))
+		}
+	})
+}
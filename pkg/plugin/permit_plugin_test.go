@@ -0,0 +1,119 @@
+package plugin
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+type permitHandleStub struct {
+	framework.Handle
+	waitingPods []*waitingPodStub
+}
+
+func (h *permitHandleStub) IterateOverWaitingPods(callback func(framework.WaitingPod)) {
+	for _, waitingPod := range h.waitingPods {
+		callback(waitingPod)
+	}
+}
+
+type waitingPodStub struct {
+	framework.WaitingPod
+	pod      *v1.Pod
+	allowed  bool
+	rejected bool
+}
+
+func (w *waitingPodStub) GetPod() *v1.Pod {
+	return w.pod
+}
+
+func (w *waitingPodStub) Allow(string) {
+	w.allowed = true
+}
+
+func (w *waitingPodStub) Reject(string, string) {
+	w.rejected = true
+}
+
+var _ = Describe("VGPUSchedulerPlugin Permit", func() {
+	var (
+		plugin    *VGPUSchedulerPlugin
+		handle    *permitHandleStub
+		indexer   cache.Indexer
+		ctx       context.Context
+		testState *framework.CycleState
+	)
+
+	newGroupPod := func(name string, minMember string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "default",
+				UID:         uuid.NewUUID(),
+				Labels:      map[string]string{PodGroupLabel: "job"},
+				Annotations: map[string]string{PodGroupMinMemberAnnotation: minMember},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		testState = framework.NewCycleState()
+		handle = &permitHandleStub{}
+		indexer = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		plugin = &VGPUSchedulerPlugin{
+			handle:              handle,
+			podlister:           listersv1.NewPodLister(indexer),
+			podGroupWaitTimeout: time.Minute,
+		}
+	})
+
+	It("should permit pods without pod group", func() {
+		pod := newGroupPod("worker-0", "3")
+		pod.Labels = nil
+		status, _ := plugin.Permit(ctx, testState, pod, "node")
+		Expect(status.IsSuccess()).To(BeTrue())
+	})
+
+	It("should reject pods with an invalid min member", func() {
+		status, _ := plugin.Permit(ctx, testState, newGroupPod("worker-0", "zero"), "node")
+		Expect(status.Code()).To(Equal(framework.UnschedulableAndUnresolvable))
+	})
+
+	It("should wait until the pod group reaches min member", func() {
+		status, timeout := plugin.Permit(ctx, testState, newGroupPod("worker-0", "3"), "node")
+		Expect(status.Code()).To(Equal(framework.Wait))
+		Expect(timeout).To(Equal(time.Minute))
+
+		waitingPod := &waitingPodStub{pod: newGroupPod("worker-1", "3")}
+		handle.waitingPods = append(handle.waitingPods, waitingPod)
+		boundPod := newGroupPod("worker-2", "3")
+		boundPod.Spec.NodeName = "node"
+		Expect(indexer.Add(boundPod)).To(Succeed())
+
+		status, _ = plugin.Permit(ctx, testState, newGroupPod("worker-3", "3"), "node")
+		Expect(status.IsSuccess()).To(BeTrue())
+		Expect(waitingPod.allowed).To(BeTrue())
+	})
+
+	It("should reject the waiting pods of the group on Unreserve", func() {
+		member := &waitingPodStub{pod: newGroupPod("worker-1", "3")}
+		other := &waitingPodStub{pod: newGroupPod("other-0", "3")}
+		other.pod.Labels[PodGroupLabel] = "other"
+		handle.waitingPods = append(handle.waitingPods, member, other)
+		plugin.cache = newDeviceCache()
+
+		plugin.Unreserve(ctx, testState, newGroupPod("worker-0", "3"), "node")
+		Expect(member.rejected).To(BeTrue())
+		Expect(other.rejected).To(BeFalse())
+	})
+})
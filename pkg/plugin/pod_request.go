@@ -0,0 +1,111 @@
+package plugin
+
+import (
+	"strings"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/device"
+	"github.com/coldzerofear/vgpu-manager/pkg/scheduler/filter"
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const podRequestKey framework.StateKey = "PodRequest"
+
+// containerRequest is the vGPU resources requested by a container.
+type containerRequest struct {
+	Name   string
+	Number int
+	Cores  int
+	Memory int
+}
+
+// podRequest summarises the vGPU requests and scheduling constraints of a pod.
+// It is computed once in PreFilter and must not be modified afterwards.
+type podRequest struct {
+	containers  []containerRequest
+	totalNumber int
+
+	memoryPolicy     string
+	memoryPolicyFunc func(info *device.NodeConfigInfo) error
+	nodePolicy       string
+	devicePolicy     string
+	topologyMode     string
+
+	deviceConstraints deviceConstraints
+	// invalidAnnotations is the error parsing the scheduling annotations of the pod.
+	invalidAnnotations error
+}
+
+// Clone returns the pod request itself as it is immutable.
+func (r *podRequest) Clone() framework.StateData {
+	return r
+}
+
+// schedulingPolicies are the scheduling policies applied to the pods that do not set them by annotation.
+type schedulingPolicies struct {
+	nodePolicy   string
+	devicePolicy string
+}
+
+func newPodRequest(pod *v1.Pod, defaultPolicies schedulingPolicies) *podRequest {
+	request := &podRequest{}
+	for i := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[i]
+		if !util.IsVGPURequiredContainer(container) {
+			continue
+		}
+		containerReq := containerRequest{
+			Name:   container.Name,
+			Number: util.GetResourceOfContainer(container, util.VGPUNumberResourceName),
+			Cores:  util.GetResourceOfContainer(container, util.VGPUCoreResourceName),
+			Memory: util.GetResourceOfContainer(container, util.VGPUMemoryResourceName),
+		}
+		request.containers = append(request.containers, containerReq)
+		request.totalNumber += containerReq.Number
+	}
+	annotations := pod.GetAnnotations()
+	request.memoryPolicy = strings.ToLower(annotations[util.MemorySchedulerPolicyAnnotation])
+	request.memoryPolicyFunc = filter.GetMemoryPolicyFunc(pod)
+	request.nodePolicy = strings.ToLower(annotations[util.NodeSchedulerPolicyAnnotation])
+	if len(request.nodePolicy) == 0 {
+		request.nodePolicy = defaultPolicies.nodePolicy
+	}
+	request.devicePolicy = strings.ToLower(annotations[util.DeviceSchedulerPolicyAnnotation])
+	if len(request.devicePolicy) == 0 {
+		request.devicePolicy = defaultPolicies.devicePolicy
+	}
+	request.topologyMode = strings.ToLower(annotations[util.DeviceTopologyModeAnnotation])
+	request.deviceConstraints, request.invalidAnnotations = newDeviceConstraints(annotations)
+	return request
+}
+
+// splitAnnotationList splits a comma separated annotation value into upper case items.
+func splitAnnotationList(annotations map[string]string, key string) []string {
+	value, ok := annotations[key]
+	if !ok {
+		return nil
+	}
+	var items []string
+	for _, item := range strings.Split(strings.ToUpper(value), ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getPodRequest returns the pod request written by PreFilter, computing it when
+// the extension point runs without PreFilter (e.g. the pod is only being bound).
+func (p *VGPUSchedulerPlugin) getPodRequest(state *framework.CycleState, pod *v1.Pod) *podRequest {
+	if data, err := state.Read(podRequestKey); err == nil {
+		return data.(*podRequest)
+	}
+	request := newPodRequest(pod, p.defaultPolicies)
+	state.Write(podRequestKey, request)
+	return request
+}
+
+func (p *VGPUSchedulerPlugin) isVGPUResourcePod(state *framework.CycleState, pod *v1.Pod) bool {
+	return p.getPodRequest(state, pod).totalNumber > 0
+}
@@ -0,0 +1,69 @@
+package plugin
+
+import (
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+var _ = Describe("VGPUSchedulerPlugin podRequest", func() {
+	var testPod *v1.Pod
+
+	BeforeEach(func() {
+		testPod = &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-pod",
+				Namespace: "default",
+				UID:       uuid.NewUUID(),
+				Annotations: map[string]string{
+					util.DeviceTopologyModeAnnotation: "Link",
+					PodIncludeGPUIndexAnnotation:      "0, 2,",
+				},
+			},
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{{
+					Name: "gpu",
+					Resources: v1.ResourceRequirements{
+						Limits: v1.ResourceList{
+							util.VGPUNumberResourceName: resource.MustParse("2"),
+							util.VGPUCoreResourceName:   resource.MustParse("50"),
+							util.VGPUMemoryResourceName: resource.MustParse("1024"),
+						},
+					},
+				}, {
+					Name: "sidecar",
+				}},
+			},
+		}
+	})
+
+	It("should summarise the vGPU requests of the pod", func() {
+		request := newPodRequest(testPod, schedulingPolicies{
+			nodePolicy:   string(util.BinpackPolicy),
+			devicePolicy: string(util.SpreadPolicy),
+		})
+		Expect(request.totalNumber).To(Equal(2))
+		Expect(request.containers).To(Equal([]containerRequest{{Name: "gpu", Number: 2, Cores: 50, Memory: 1024}}))
+		Expect(request.nodePolicy).To(Equal(string(util.BinpackPolicy)))
+		Expect(request.devicePolicy).To(Equal(string(util.SpreadPolicy)))
+		Expect(request.topologyMode).To(Equal(string(util.LinkTopology)))
+		Expect(request.deviceConstraints.includeIndexes).To(Equal(sets.New(0, 2)))
+		Expect(request.deviceConstraints.excludeIndexes).To(BeNil())
+		Expect(request.invalidAnnotations).NotTo(HaveOccurred())
+	})
+
+	It("should be computed once per scheduling cycle", func() {
+		plugin := &VGPUSchedulerPlugin{}
+		state := framework.NewCycleState()
+		request := plugin.getPodRequest(state, testPod)
+		testPod.Spec.Containers[0].Resources.Limits = nil
+		Expect(plugin.getPodRequest(state, testPod)).To(BeIdenticalTo(request))
+		Expect(plugin.isVGPUResourcePod(state, testPod)).To(BeTrue())
+	})
+})
@@ -0,0 +1,309 @@
+package plugin
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"math/rand"
+	"slices"
+	"sort"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/device"
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+	"k8s.io/klog/v2"
+	extenderv1 "k8s.io/kube-scheduler/extender/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/preemption"
+	schedutil "k8s.io/kubernetes/pkg/scheduler/util"
+)
+
+const (
+	// minCandidateNodesPercentage and minCandidateNodesAbsolute are the same as the default preemption.
+	minCandidateNodesPercentage = 10
+	minCandidateNodesAbsolute   = 100
+)
+
+var _ framework.PostFilterPlugin = &VGPUSchedulerPlugin{}
+
+// PostFilter preempts the lower priority vGPU pods occupying the fewest GPUs
+// needed to make room for the vGPU pod, and nominates the node for it.
+func (p *VGPUSchedulerPlugin) PostFilter(ctx context.Context, state *framework.CycleState, pod *v1.Pod, m framework.NodeToStatusReader) (*framework.PostFilterResult, *framework.Status) {
+	if !p.isVGPUResourcePod(state, pod) {
+		return nil, framework.NewStatus(framework.Unschedulable, "pod did not request vGPU")
+	}
+	p.reportFilterDiagnosis(state, pod)
+	result, status := p.evaluator.Preempt(ctx, state, pod, m)
+	if msg := status.Message(); len(msg) > 0 {
+		return result, framework.NewStatus(status.Code(), "vGPU preemption: "+msg)
+	}
+	return result, status
+}
+
+// devicePreemption implements the preemption.Interface selecting victims by the GPUs they occupy.
+type devicePreemption struct {
+	plugin *VGPUSchedulerPlugin
+}
+
+var _ preemption.Interface = &devicePreemption{}
+
+func (dp *devicePreemption) GetOffsetAndNumCandidates(numNodes int32) (int32, int32) {
+	n := (numNodes * minCandidateNodesPercentage) / 100
+	n = min(max(n, minCandidateNodesAbsolute), numNodes)
+	return rand.Int31n(numNodes), n
+}
+
+func (dp *devicePreemption) CandidatesToVictimsMap(candidates []preemption.Candidate) map[string]*extenderv1.Victims {
+	m := make(map[string]*extenderv1.Victims, len(candidates))
+	for _, c := range candidates {
+		m[c.Name()] = c.Victims()
+	}
+	return m
+}
+
+func (dp *devicePreemption) PodEligibleToPreemptOthers(_ context.Context, pod *v1.Pod, nominatedNodeStatus *framework.Status) (bool, string) {
+	if pod.Spec.PreemptionPolicy != nil && *pod.Spec.PreemptionPolicy == v1.PreemptNever {
+		return false, "not eligible due to preemptionPolicy=Never."
+	}
+	nomNodeName := pod.Status.NominatedNodeName
+	if len(nomNodeName) == 0 || nominatedNodeStatus.Code() == framework.UnschedulableAndUnresolvable {
+		return true, ""
+	}
+	if nodeInfo, _ := dp.plugin.handle.SnapshotSharedLister().NodeInfos().Get(nomNodeName); nodeInfo != nil {
+		podPriority := corev1helpers.PodPriority(pod)
+		for _, pi := range nodeInfo.Pods {
+			if corev1helpers.PodPriority(pi.Pod) < podPriority && pi.Pod.DeletionTimestamp != nil {
+				return false, "not eligible due to a terminating pod on the nominated node."
+			}
+		}
+	}
+	return true, ""
+}
+
+// SelectVictimsOnNode removes the lower priority vGPU pods, and the pods borrowing the elastic
+// quota resources the pod reclaims, GPU by GPU, starting from the GPUs shared by the fewest of
+// them, until the device request of the pod fits, then reprieves as many of them as possible.
+func (dp *devicePreemption) SelectVictimsOnNode(ctx context.Context, state *framework.CycleState, pod *v1.Pod,
+	nodeInfo *framework.NodeInfo, pdbs []*policy.PodDisruptionBudget) ([]*v1.Pod, int, *framework.Status) {
+	logger := klog.FromContext(ctx)
+	fh := dp.plugin.handle
+	podPriority := corev1helpers.PodPriority(pod)
+	quotaState := getElasticQuotaState(state)
+	potentialVictims := map[types.UID]*framework.PodInfo{}
+	for _, pi := range nodeInfo.Pods {
+		lowerPriority := corev1helpers.PodPriority(pi.Pod) < podPriority
+		if util.IsVGPUResourcePod(pi.Pod) && dp.plugin.isElasticQuotaVictim(quotaState, pi.Pod, lowerPriority) {
+			potentialVictims[pi.Pod.UID] = pi
+		}
+	}
+	if len(potentialVictims) == 0 {
+		return nil, 0, framework.NewStatus(framework.UnschedulableAndUnresolvable, "No vGPU preemption victims found for incoming pod")
+	}
+
+	adjustment, _ := getPodsAdjustment(state, nodeInfo.GetName())
+	nodePods := adjustment.apply(dp.plugin.cache.PodsOnNode(nodeInfo.GetName()))
+	request := dp.plugin.getPodRequest(state, pod)
+	// The elastic quota of the pod is checked in PreFilter, so it must be passed again without the victims.
+	quotaFits := func(sets.Set[types.UID]) bool { return true }
+	if quotaState != nil {
+		quotas, _ := dp.plugin.elasticQuotas.list()
+		usages, err := dp.plugin.elasticQuotaUsages(quotas, pod)
+		if err != nil {
+			return nil, 0, framework.AsStatus(err)
+		}
+		quotaFits = func(removed sets.Set[types.UID]) bool {
+			removedPods := make([]*v1.Pod, 0, removed.Len())
+			for uid := range removed {
+				removedPods = append(removedPods, potentialVictims[uid].Pod)
+			}
+			return dp.plugin.elasticQuotaFitsWithout(usages, pod, request, removedPods)
+		}
+	}
+	selected := sets.New[types.UID]()
+	fits := false
+	for _, pis := range groupVictimsByDevice(potentialVictims) {
+		for _, pi := range pis {
+			selected.Insert(pi.Pod.UID)
+		}
+		if fits = devicesFitWithout(nodeInfo.Node(), nodePods, selected, pod, request) && quotaFits(selected); fits {
+			break
+		}
+	}
+	if !fits {
+		return nil, 0, framework.NewStatus(framework.UnschedulableAndUnresolvable, "insufficient GPU or elastic quota even after preempting all potential victims")
+	}
+
+	removePod := func(pi *framework.PodInfo) error {
+		if err := nodeInfo.RemovePod(logger, pi.Pod); err != nil {
+			return err
+		}
+		return fh.RunPreFilterExtensionRemovePod(ctx, state, pod, pi, nodeInfo).AsError()
+	}
+	addPod := func(pi *framework.PodInfo) error {
+		nodeInfo.AddPodInfo(pi)
+		return fh.RunPreFilterExtensionAddPod(ctx, state, pod, pi, nodeInfo).AsError()
+	}
+	var selectedVictims []*framework.PodInfo
+	for uid := range selected {
+		pi := potentialVictims[uid]
+		selectedVictims = append(selectedVictims, pi)
+		if err := removePod(pi); err != nil {
+			return nil, 0, framework.AsStatus(err)
+		}
+	}
+	if status := fh.RunFilterPluginsWithNominatedPods(ctx, state, pod, nodeInfo); !status.IsSuccess() {
+		return nil, 0, status
+	}
+
+	var victims []*v1.Pod
+	numViolatingVictim := 0
+	// Reprieve the pods using their own resources before the ones borrowing resources.
+	sort.Slice(selectedVictims, func(i, j int) bool {
+		if bi, bj := isBorrowedPod(selectedVictims[i].Pod), isBorrowedPod(selectedVictims[j].Pod); bi != bj {
+			return bj
+		}
+		return schedutil.MoreImportantPod(selectedVictims[i].Pod, selectedVictims[j].Pod)
+	})
+	reprievePod := func(pi *framework.PodInfo) (bool, error) {
+		if err := addPod(pi); err != nil {
+			return false, err
+		}
+		selected.Delete(pi.Pod.UID)
+		fits := fh.RunFilterPluginsWithNominatedPods(ctx, state, pod, nodeInfo).IsSuccess() && quotaFits(selected)
+		if !fits {
+			selected.Insert(pi.Pod.UID)
+			if err := removePod(pi); err != nil {
+				return false, err
+			}
+			victims = append(victims, pi.Pod)
+			logger.V(5).Info("Pod is a potential vGPU preemption victim on node", "pod", klog.KObj(pi.Pod), "node", nodeInfo.GetName())
+		}
+		return fits, nil
+	}
+	violatingVictims, nonViolatingVictims := filterPodsWithPDBViolation(selectedVictims, pdbs)
+	for _, pi := range violatingVictims {
+		if fits, err := reprievePod(pi); err != nil {
+			return nil, 0, framework.AsStatus(err)
+		} else if !fits {
+			numViolatingVictim++
+		}
+	}
+	for _, pi := range nonViolatingVictims {
+		if _, err := reprievePod(pi); err != nil {
+			return nil, 0, framework.AsStatus(err)
+		}
+	}
+	sort.Slice(victims, func(i, j int) bool { return schedutil.MoreImportantPod(victims[i], victims[j]) })
+	return victims, numViolatingVictim, framework.NewStatus(framework.Success)
+}
+
+func (dp *devicePreemption) OrderedScoreFuncs(context.Context, map[string]*extenderv1.Victims) []func(node string) int64 {
+	return nil
+}
+
+// groupVictimsByDevice groups the victims by the GPU they occupy, ordered by the number of
+// borrowed victims on the GPU, the number of victims and their total priority, so that the
+// GPUs lent to other elastic quotas and then the cheapest GPUs are freed first.
+func groupVictimsByDevice(victims map[types.UID]*framework.PodInfo) [][]*framework.PodInfo {
+	devicePods := map[int][]*framework.PodInfo{}
+	for _, pi := range victims {
+		ids := sets.New[int]()
+		for _, container := range device.GetPodAssignDevices(pi.Pod) {
+			for _, dev := range container.Devices {
+				ids.Insert(dev.Id)
+			}
+		}
+		for id := range ids {
+			devicePods[id] = append(devicePods[id], pi)
+		}
+	}
+	prioritySum := func(pis []*framework.PodInfo) int64 {
+		var sum int64
+		for _, pi := range pis {
+			sum += int64(corev1helpers.PodPriority(pi.Pod))
+		}
+		return sum
+	}
+	borrowedCount := func(pis []*framework.PodInfo) int {
+		count := 0
+		for _, pi := range pis {
+			if isBorrowedPod(pi.Pod) {
+				count++
+			}
+		}
+		return count
+	}
+	ids := slices.Collect(maps.Keys(devicePods))
+	slices.SortFunc(ids, func(a, b int) int {
+		return cmp.Or(
+			cmp.Compare(borrowedCount(devicePods[b]), borrowedCount(devicePods[a])),
+			cmp.Compare(len(devicePods[a]), len(devicePods[b])),
+			cmp.Compare(prioritySum(devicePods[a]), prioritySum(devicePods[b])),
+			cmp.Compare(a, b),
+		)
+	})
+	groups := make([][]*framework.PodInfo, 0, len(ids))
+	for _, id := range ids {
+		groups = append(groups, devicePods[id])
+	}
+	return groups
+}
+
+// devicesFitWithout simulates the device allocation of the pod on the node without the removed pods.
+func devicesFitWithout(node *v1.Node, pods []*v1.Pod, removed sets.Set[types.UID], pod *v1.Pod, request *podRequest) bool {
+	remaining := slices.DeleteFunc(slices.Clone(pods), func(p *v1.Pod) bool {
+		return removed.Has(p.UID)
+	})
+	devNodeInfo, err := device.NewNodeInfo(node, remaining)
+	if err != nil {
+		return false
+	}
+	if matched, err := maskDevices(devNodeInfo, request.deviceConstraints, quarantinedGPUs(node)); err != nil || matched == 0 {
+		return false
+	}
+	_, err = allocateDevices(devNodeInfo, pod, request)
+	return err == nil
+}
+
+// filterPodsWithPDBViolation groups the given pods into two groups of violating pods and
+// non-violating pods based on whether their PDBs will be violated if they are preempted.
+func filterPodsWithPDBViolation(podInfos []*framework.PodInfo, pdbs []*policy.PodDisruptionBudget) (violatingPodInfos, nonViolatingPodInfos []*framework.PodInfo) {
+	pdbsAllowed := make([]int32, len(pdbs))
+	for i, pdb := range pdbs {
+		pdbsAllowed[i] = pdb.Status.DisruptionsAllowed
+	}
+	for _, podInfo := range podInfos {
+		pod := podInfo.Pod
+		pdbForPodIsViolated := false
+		if len(pod.Labels) != 0 {
+			for i, pdb := range pdbs {
+				if pdb.Namespace != pod.Namespace {
+					continue
+				}
+				selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+				if err != nil || selector.Empty() || !selector.Matches(labels.Set(pod.Labels)) {
+					continue
+				}
+				if _, exist := pdb.Status.DisruptedPods[pod.Name]; exist {
+					continue
+				}
+				pdbsAllowed[i]--
+				if pdbsAllowed[i] < 0 {
+					pdbForPodIsViolated = true
+				}
+			}
+		}
+		if pdbForPodIsViolated {
+			violatingPodInfos = append(violatingPodInfos, podInfo)
+		} else {
+			nonViolatingPodInfos = append(nonViolatingPodInfos, podInfo)
+		}
+	}
+	return violatingPodInfos, nonViolatingPodInfos
+}
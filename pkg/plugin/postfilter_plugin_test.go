@@ -0,0 +1,128 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("VGPUSchedulerPlugin PostFilter", func() {
+	var (
+		plugin      *VGPUSchedulerPlugin
+		ctx         context.Context
+		testState   *framework.CycleState
+		nodeInfo    *framework.NodeInfo
+		pendingPod  *v1.Pod
+		victimPods  []*v1.Pod
+		newVGPUPod  func(name string, priority int32, memory string, realAlloc string) *v1.Pod
+		testNodeObj *v1.Node
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		testState = framework.NewCycleState()
+		plugin = &VGPUSchedulerPlugin{cache: newDeviceCache()}
+		plugin.handle = &preemptionHandleStub{plugin: plugin}
+		heartbeat, _ := metav1.NowMicro().MarshalText()
+		testNodeObj = &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-node",
+				UID:  uuid.NewUUID(),
+				Annotations: map[string]string{
+					util.NodeDeviceHeartbeatAnnotation: string(heartbeat),
+					util.NodeConfigInfoAnnotation:      `{"deviceSplit":10,"coresScaling":1,"memoryFactor":1,"memoryScaling":1}`,
+					util.NodeDeviceRegisterAnnotation: `[{"id":0,"uuid":"GPU-0","core":100,"memory":10240,"number":10,"healthy":true},` +
+						`{"id":1,"uuid":"GPU-1","core":100,"memory":10240,"number":10,"healthy":true}]`,
+				},
+			},
+			Status: v1.NodeStatus{
+				Allocatable: v1.ResourceList{util.VGPUNumberResourceName: resource.MustParse("20")},
+			},
+		}
+		newVGPUPod = func(name string, priority int32, memory string, realAlloc string) *v1.Pod {
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        name,
+					Namespace:   "default",
+					UID:         uuid.NewUUID(),
+					Annotations: map[string]string{},
+				},
+				Spec: v1.PodSpec{
+					Priority: ptr.To(priority),
+					Containers: []v1.Container{{
+						Name: "default",
+						Resources: v1.ResourceRequirements{
+							Limits: v1.ResourceList{
+								util.VGPUNumberResourceName: resource.MustParse("1"),
+								util.VGPUMemoryResourceName: resource.MustParse(memory),
+							},
+						},
+					}},
+				},
+			}
+			if realAlloc != "" {
+				pod.Spec.NodeName = testNodeObj.Name
+				pod.Annotations[util.PodVGPURealAllocAnnotation] = realAlloc
+			}
+			return pod
+		}
+		// GPU-0 is half used by one pod, GPU-1 is fully used by two pods.
+		victimPods = []*v1.Pod{
+			newVGPUPod("victim-0", 10, "5120", "default[0_GPU-0_0_5120]"),
+			newVGPUPod("victim-1", 1, "5120", "default[1_GPU-1_0_5120]"),
+			newVGPUPod("victim-2", 1, "5120", "default[1_GPU-1_0_5120]"),
+		}
+		pendingPod = newVGPUPod("pending-pod", 100, "10240", "")
+
+		nodeInfo = framework.NewNodeInfo()
+		nodeInfo.SetNode(testNodeObj)
+		plugin.cache.updateNode(testNodeObj)
+		for _, pod := range victimPods {
+			nodeInfo.AddPod(pod)
+			plugin.cache.updatePod(pod)
+		}
+	})
+
+	It("should select the fewest victims freeing a whole GPU", func() {
+		Expect(plugin.Filter(ctx, testState, pendingPod, nodeInfo).IsSuccess()).To(BeFalse())
+
+		dp := &devicePreemption{plugin: plugin}
+		victims, numViolating, status := dp.SelectVictimsOnNode(ctx, testState.Clone(), pendingPod, nodeInfo.Snapshot(), nil)
+		Expect(status.IsSuccess()).To(BeTrue())
+		Expect(numViolating).To(Equal(0))
+		Expect(victims).To(HaveLen(1))
+		Expect(victims[0].Name).To(Equal("victim-0"))
+	})
+
+	It("should not preempt pods with higher priority", func() {
+		pendingPod.Spec.Priority = ptr.To[int32](0)
+		dp := &devicePreemption{plugin: plugin}
+		_, _, status := dp.SelectVictimsOnNode(ctx, testState.Clone(), pendingPod, nodeInfo.Snapshot(), nil)
+		Expect(status.Code()).To(Equal(framework.UnschedulableAndUnresolvable))
+	})
+})
+
+type preemptionHandleStub struct {
+	framework.Handle
+	plugin *VGPUSchedulerPlugin
+}
+
+func (h *preemptionHandleStub) RunPreFilterExtensionAddPod(ctx context.Context, state *framework.CycleState, podToSchedule *v1.Pod, podInfoToAdd *framework.PodInfo, nodeInfo *framework.NodeInfo) *framework.Status {
+	return h.plugin.AddPod(ctx, state, podToSchedule, podInfoToAdd, nodeInfo)
+}
+
+func (h *preemptionHandleStub) RunPreFilterExtensionRemovePod(ctx context.Context, state *framework.CycleState, podToSchedule *v1.Pod, podInfoToRemove *framework.PodInfo, nodeInfo *framework.NodeInfo) *framework.Status {
+	return h.plugin.RemovePod(ctx, state, podToSchedule, podInfoToRemove, nodeInfo)
+}
+
+func (h *preemptionHandleStub) RunFilterPluginsWithNominatedPods(ctx context.Context, state *framework.CycleState, pod *v1.Pod, info *framework.NodeInfo) *framework.Status {
+	return h.plugin.Filter(ctx, state, pod, info)
+}
@@ -0,0 +1,146 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/metrics"
+	"github.com/coldzerofear/vgpu-manager/pkg/client"
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+var (
+	_ framework.PreBindPlugin  = &VGPUSchedulerPlugin{}
+	_ framework.PostBindPlugin = &VGPUSchedulerPlugin{}
+)
+
+const bindTransactionKey framework.StateKey = "BindTransaction"
+
+// bindTransaction tracks the binding of a pod from PreBind until PostBind or Unreserve:
+// the metadata patched on the pod, reverted when the binding fails, and the release of
+// the binding throttle of the node.
+type bindTransaction struct {
+	change  *podMetadataChange
+	release func(bound bool)
+	once    sync.Once
+}
+
+func (t *bindTransaction) Clone() framework.StateData {
+	return t
+}
+
+// finish releases the binding throttle of the node once, with whether the pod was bound.
+func (t *bindTransaction) finish(bound bool) {
+	if t.release != nil {
+		t.once.Do(func() { t.release(bound) })
+	}
+}
+
+func getBindTransaction(state *framework.CycleState) *bindTransaction {
+	data, err := state.Read(bindTransactionKey)
+	if err != nil {
+		return nil
+	}
+	return data.(*bindTransaction)
+}
+
+// PreBind patches the vGPU scheduling metadata on the pod before any bind plugin binds it,
+// so that the device plugin finds the pre-allocated devices once the pod lands on the node.
+func (p *VGPUSchedulerPlugin) PreBind(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) *framework.Status {
+	logger := klog.FromContext(ctx)
+	transaction := &bindTransaction{}
+	patchData := client.PatchMetadata{
+		Annotations: map[string]string{},
+		Labels:      map[string]string{},
+	}
+	if !p.isVGPUResourcePod(state, pod) {
+		patchData.Labels[util.PodAssignedPhaseLabel] = string(util.AssignPhaseSucceed)
+		patchData.Annotations[util.PodPredicateTimeAnnotation] = fmt.Sprintf("%d", uint64(math.MaxUint64))
+	} else {
+		data, err := state.Read(p.preAllocateDeviceKey(nodeName))
+		if err != nil {
+			errMsg := "getting pre allocated devices for node failed"
+			logger.Error(err, errMsg, "pod", klog.KObj(pod), "node", nodeName)
+			return framework.NewStatus(framework.Error, errMsg)
+		}
+		preAllocate := data.(preAllocateDevice)
+		// Throttling is to prevent excessive binding speed on a node from causing device plugin allocation failed.
+		// The node is held until the binding finished in PostBind or Unreserve.
+		startTime := time.Now()
+		release, err := p.throttle.Acquire(ctx, nodeName, pod)
+		if err != nil {
+			logger.Error(err, "waiting for the previous binding on node failed", "pod", klog.KObj(pod), "node", nodeName)
+			return framework.NewStatus(framework.Error, err.Error())
+		}
+		klog.V(5).Infof("waiting for binding node <%s> took %d milliseconds", nodeName, time.Since(startTime).Milliseconds())
+		metrics.BindWaitDuration.Observe(metrics.SinceInSeconds(startTime))
+		transaction.release = release
+		predicateTime := fmt.Sprintf("%d", metav1.NowMicro().UnixNano())
+		patchData.Labels[util.PodAssignedPhaseLabel] = string(util.AssignPhaseAllocating)
+		patchData.Annotations[util.PodPredicateNodeAnnotation] = nodeName
+		patchData.Annotations[util.PodVGPUPreAllocAnnotation] = string(preAllocate)
+		patchData.Annotations[util.PodVGPURealAllocAnnotation] = ""
+		patchData.Annotations[util.PodPredicateTimeAnnotation] = predicateTime
+		if quotaState := getElasticQuotaState(state); quotaState != nil && quotaState.borrowing {
+			patchData.Labels[BorrowedPodLabel] = "true"
+		}
+	}
+	state.Write(bindTransactionKey, transaction)
+
+	change := newPodMetadataChange(pod, patchData)
+	attempts := 0
+	err := retry.OnError(retry.DefaultRetry, util.ShouldRetry, func() error {
+		if attempts++; attempts > 1 {
+			metrics.PatchRetries.Inc()
+		}
+		// The pod is held by the scheduler cache, the patched pod is only kept by the informer.
+		return client.PatchPodMetadata(p.handle.ClientSet(), pod.DeepCopy(), patchData)
+	})
+	if err != nil {
+		logger.Error(err, "patch vGPU metadata failed", "pod", klog.KObj(pod), "node", nodeName)
+		metrics.BindFailures.WithLabelValues(metrics.BindStagePatch).Inc()
+		return framework.NewStatus(framework.Error, err.Error())
+	}
+	transaction.change = change
+	logger.V(4).Info("Patched vGPU metadata of pod", "pod", klog.KObj(pod), "node", nodeName)
+	return framework.NewStatus(framework.Success, "")
+}
+
+// PostBind releases the binding throttle of the node, which now waits for the device plugin to pick up the pod.
+func (p *VGPUSchedulerPlugin) PostBind(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) {
+	if transaction := getBindTransaction(state); transaction != nil {
+		transaction.finish(true)
+	}
+}
+
+// rollbackBind reverts the metadata patched in PreBind when the binding failed, so that the
+// pod is left as it was and scheduled again by the next scheduling cycle.
+func (p *VGPUSchedulerPlugin) rollbackBind(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) {
+	transaction := getBindTransaction(state)
+	if transaction == nil {
+		return
+	}
+	transaction.finish(false)
+	if transaction.change == nil {
+		return
+	}
+	logger := klog.FromContext(ctx)
+	if err := transaction.change.revert(ctx, p.handle.ClientSet(), pod); err != nil {
+		logger.Error(err, "reverting vGPU metadata of pod failed", "pod", klog.KObj(pod), "node", nodeName)
+		metrics.BindFailures.WithLabelValues(metrics.BindStageRollback).Inc()
+		p.handle.EventRecorder().Eventf(pod, nil, v1.EventTypeWarning, "VGPUBindRollbackFailed", "Binding",
+			"Binding to node %s failed, reverting the vGPU metadata failed: %v", nodeName, err)
+		return
+	}
+	logger.V(4).Info("Reverted vGPU metadata of pod", "pod", klog.KObj(pod), "node", nodeName)
+	p.handle.EventRecorder().Eventf(pod, nil, v1.EventTypeWarning, "VGPUBindRolledBack", "Binding",
+		"Binding to node %s failed, the vGPU metadata was reverted", nodeName)
+}
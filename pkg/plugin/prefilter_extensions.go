@@ -0,0 +1,103 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/device"
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+var _ framework.PreFilterExtensions = &VGPUSchedulerPlugin{}
+
+// podsAdjustment records the pods added to or removed from a node
+// while the framework evaluates preemption in the scheduling cycle.
+type podsAdjustment struct {
+	added   map[types.UID]*v1.Pod
+	removed sets.Set[types.UID]
+}
+
+func (a *podsAdjustment) Clone() framework.StateData {
+	added := make(map[types.UID]*v1.Pod, len(a.added))
+	for uid, pod := range a.added {
+		added[uid] = pod
+	}
+	return &podsAdjustment{added: added, removed: a.removed.Clone()}
+}
+
+// apply returns the pods on the node after the adjustment.
+func (a *podsAdjustment) apply(pods []*v1.Pod) []*v1.Pod {
+	adjusted := make([]*v1.Pod, 0, len(pods)+len(a.added))
+	for _, pod := range pods {
+		if _, ok := a.added[pod.UID]; ok || a.removed.Has(pod.UID) {
+			continue
+		}
+		adjusted = append(adjusted, pod)
+	}
+	for _, pod := range a.added {
+		adjusted = append(adjusted, pod)
+	}
+	return adjusted
+}
+
+func podsAdjustmentKey(nodeName string) framework.StateKey {
+	return framework.StateKey("PodsAdjustment_" + nodeName)
+}
+
+func getPodsAdjustment(state *framework.CycleState, nodeName string) (*podsAdjustment, bool) {
+	data, err := state.Read(podsAdjustmentKey(nodeName))
+	if err != nil {
+		return &podsAdjustment{added: map[types.UID]*v1.Pod{}, removed: sets.New[types.UID]()}, false
+	}
+	return data.(*podsAdjustment), true
+}
+
+// AddPod updates the device state of the node as if the pod was running on it.
+func (p *VGPUSchedulerPlugin) AddPod(ctx context.Context, state *framework.CycleState, podToSchedule *v1.Pod, podInfoToAdd *framework.PodInfo, nodeInfo *framework.NodeInfo) *framework.Status {
+	podToAdd := podInfoToAdd.Pod
+	if !util.IsVGPUResourcePod(podToAdd) {
+		return framework.NewStatus(framework.Success, "")
+	}
+	adjustment, _ := getPodsAdjustment(state, nodeInfo.GetName())
+	adjustment.removed.Delete(podToAdd.UID)
+	adjustment.added[podToAdd.UID] = podToAdd
+	state.Write(podsAdjustmentKey(nodeInfo.GetName()), adjustment)
+
+	data, err := state.Read(devNodeInfoKey(nodeInfo.GetName()))
+	if err != nil {
+		if _, err = p.createDevNodeInfo(state, nodeInfo); err != nil {
+			return framework.AsStatus(err)
+		}
+		return framework.NewStatus(framework.Success, "")
+	}
+	devNodeInfo := data.(*device.NodeInfo).Clone().(*device.NodeInfo)
+	if err = addPodDevices(devNodeInfo, podToAdd); err != nil {
+		return framework.AsStatus(err)
+	}
+	state.Write(devNodeInfoKey(nodeInfo.GetName()), devNodeInfo)
+	klog.FromContext(ctx).V(5).Info("Added pod devices to node", "pod", klog.KObj(podToAdd), "node", nodeInfo.GetName())
+	return framework.NewStatus(framework.Success, "")
+}
+
+// RemovePod updates the device state of the node as if the pod was no longer running on it.
+func (p *VGPUSchedulerPlugin) RemovePod(ctx context.Context, state *framework.CycleState, podToSchedule *v1.Pod, podInfoToRemove *framework.PodInfo, nodeInfo *framework.NodeInfo) *framework.Status {
+	podToRemove := podInfoToRemove.Pod
+	if !util.IsVGPUResourcePod(podToRemove) {
+		return framework.NewStatus(framework.Success, "")
+	}
+	adjustment, _ := getPodsAdjustment(state, nodeInfo.GetName())
+	delete(adjustment.added, podToRemove.UID)
+	adjustment.removed.Insert(podToRemove.UID)
+	state.Write(podsAdjustmentKey(nodeInfo.GetName()), adjustment)
+
+	// The device state does not support releasing resources, so rebuild it from the remaining pods.
+	if _, err := p.createDevNodeInfo(state, nodeInfo); err != nil {
+		return framework.AsStatus(err)
+	}
+	klog.FromContext(ctx).V(5).Info("Removed pod devices from node", "pod", klog.KObj(podToRemove), "node", nodeInfo.GetName())
+	return framework.NewStatus(framework.Success, "")
+}
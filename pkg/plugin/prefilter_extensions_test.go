@@ -0,0 +1,99 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+var _ = Describe("VGPUSchedulerPlugin PreFilterExtensions", func() {
+	var (
+		plugin    *VGPUSchedulerPlugin
+		ctx       context.Context
+		testState *framework.CycleState
+		nodeInfo  *framework.NodeInfo
+		victimPod *v1.Pod
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		testState = framework.NewCycleState()
+		plugin = &VGPUSchedulerPlugin{cache: newDeviceCache()}
+		node := &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-node",
+				UID:  uuid.NewUUID(),
+				Annotations: map[string]string{
+					util.NodeDeviceRegisterAnnotation: `[{"id":0,"uuid":"GPU-0","core":100,"memory":10240,"number":10,"healthy":true}]`,
+				},
+			},
+		}
+		nodeInfo = framework.NewNodeInfo()
+		nodeInfo.SetNode(node)
+		victimPod = &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "victim-pod",
+				Namespace: "default",
+				UID:       uuid.NewUUID(),
+				Annotations: map[string]string{
+					util.PodVGPURealAllocAnnotation: "default[0_GPU-0_100_10240]",
+				},
+			},
+			Spec: v1.PodSpec{
+				NodeName: node.Name,
+				Containers: []v1.Container{{
+					Name: "default",
+					Resources: v1.ResourceRequirements{
+						Limits: v1.ResourceList{
+							util.VGPUNumberResourceName: resource.MustParse("1"),
+						},
+					},
+				}},
+			},
+		}
+		plugin.cache.updateNode(node)
+		plugin.cache.updatePod(victimPod)
+	})
+
+	It("should release and restore the devices of the victim pod", func() {
+		devNodeInfo, err := plugin.getDevNodeInfo(testState, nodeInfo)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(devNodeInfo.GetAvailableMemory()).To(Equal(0))
+
+		status := plugin.RemovePod(ctx, testState, nil, &framework.PodInfo{Pod: victimPod}, nodeInfo)
+		Expect(status.IsSuccess()).To(BeTrue())
+		devNodeInfo, err = plugin.getDevNodeInfo(testState, nodeInfo)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(devNodeInfo.GetAvailableMemory()).To(Equal(10240))
+
+		// The adjustment must survive the device state being dropped by a failed Filter.
+		plugin.deleteDevNodeInfo(testState, nodeInfo)
+		devNodeInfo, err = plugin.getDevNodeInfo(testState, nodeInfo)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(devNodeInfo.GetAvailableMemory()).To(Equal(10240))
+
+		status = plugin.AddPod(ctx, testState, nil, &framework.PodInfo{Pod: victimPod}, nodeInfo)
+		Expect(status.IsSuccess()).To(BeTrue())
+		devNodeInfo, err = plugin.getDevNodeInfo(testState, nodeInfo)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(devNodeInfo.GetAvailableMemory()).To(Equal(0))
+	})
+
+	It("should not share adjustments between cloned cycle states", func() {
+		_, err := plugin.getDevNodeInfo(testState, nodeInfo)
+		Expect(err).NotTo(HaveOccurred())
+		clonedState := testState.Clone()
+		Expect(plugin.RemovePod(ctx, clonedState, nil, &framework.PodInfo{Pod: victimPod}, nodeInfo).IsSuccess()).To(BeTrue())
+
+		devNodeInfo, err := plugin.getDevNodeInfo(testState, nodeInfo)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(devNodeInfo.GetAvailableMemory()).To(Equal(0))
+	})
+})
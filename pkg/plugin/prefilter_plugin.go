@@ -2,6 +2,7 @@ package plugin
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/coldzerofear/vgpu-manager/pkg/util"
@@ -14,46 +15,49 @@ var _ framework.PreFilterPlugin = &VGPUSchedulerPlugin{}
 
 func (p *VGPUSchedulerPlugin) PreFilter(ctx context.Context, state *framework.CycleState, pod *v1.Pod) (*framework.PreFilterResult, *framework.Status) {
 	logger := klog.FromContext(ctx)
-	if !p.isVGPUResourcePod(state, pod) {
+	request := newPodRequest(pod, p.defaultPolicies)
+	state.Write(podRequestKey, request)
+	if request.totalNumber == 0 {
 		logger.Info("pod did not request vGPU, skipping device filtering", "pod", klog.KObj(pod))
 		return nil, framework.NewStatus(framework.Skip, "")
 	}
-	if err := p.checkDeviceRequests(pod); err != nil {
+	if err := p.checkDeviceRequests(request); err != nil {
 		logger.Error(err, "check device requests failed", "pod", klog.KObj(pod))
 		p.handle.EventRecorder().Eventf(pod, nil, v1.EventTypeWarning, "FailedFiltering", "Scheduling", err.Error())
 		return nil, framework.NewStatus(framework.UnschedulableAndUnresolvable, err.Error())
 	}
+	if err := p.checkQuotas(pod, request); err != nil {
+		var quotaErr *quotaExceededError
+		if !errors.As(err, &quotaErr) {
+			logger.Error(err, "check vGPU quotas failed", "pod", klog.KObj(pod))
+			return nil, framework.NewStatus(framework.Error, err.Error())
+		}
+		logger.Info("vGPU quota exceeded", "pod", klog.KObj(pod), "quota", quotaErr.quota, "resource", quotaErr.resource)
+		p.handle.EventRecorder().Eventf(pod, nil, v1.EventTypeWarning, "VGPUQuotaExceeded", "Scheduling", err.Error())
+		return nil, framework.NewStatus(framework.UnschedulableAndUnresolvable, err.Error())
+	}
+	if status := p.checkElasticQuota(ctx, state, pod, request); status != nil {
+		return nil, status
+	}
+	state.Write(filterDiagnosisKey, &filterDiagnosis{nodes: map[string]nodeDiagnosis{}})
 	return nil, framework.NewStatus(framework.Success, "")
 }
 
 func (p *VGPUSchedulerPlugin) PreFilterExtensions() framework.PreFilterExtensions {
-	return nil
+	return p
 }
 
-func (p *VGPUSchedulerPlugin) checkDeviceRequests(pod *v1.Pod) error {
-	for _, container := range pod.Spec.Containers {
-		if util.GetResourceOfContainer(&container, util.VGPUCoreResourceName) > util.HundredCore {
+func (p *VGPUSchedulerPlugin) checkDeviceRequests(request *podRequest) error {
+	if request.invalidAnnotations != nil {
+		return request.invalidAnnotations
+	}
+	for _, container := range request.containers {
+		if container.Cores > util.HundredCore {
 			return fmt.Errorf("container %s requests vGPU core exceeding limit, maxLimit: %d", container.Name, util.HundredCore)
 		}
-		if util.GetResourceOfContainer(&container, util.VGPUNumberResourceName) > util.MaxDeviceNumber {
+		if container.Number > util.MaxDeviceNumber {
 			return fmt.Errorf("container %s requests vGPU number exceeding limit, maxLimit: %d", container.Name, util.MaxDeviceNumber)
 		}
 	}
 	return nil
 }
-
-func (p *VGPUSchedulerPlugin) getTotalRequestVGPUByPod(state *framework.CycleState, pod *v1.Pod) int {
-	totalVGPU, err := state.Read(util.VGPUNumberResourceName)
-	if err != nil {
-		totalNum := util.GetResourceOfPod(pod, util.VGPUNumberResourceName)
-		totalVGPU = resourceNumber(totalNum)
-		state.Write(util.VGPUNumberResourceName, totalVGPU)
-		return totalNum
-	}
-	number := totalVGPU.(resourceNumber)
-	return int(number)
-}
-
-func (p *VGPUSchedulerPlugin) isVGPUResourcePod(state *framework.CycleState, pod *v1.Pod) bool {
-	return p.getTotalRequestVGPUByPod(state, pod) > 0
-}
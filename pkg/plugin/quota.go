@@ -0,0 +1,171 @@
+package plugin
+
+import (
+	"fmt"
+
+	pluginconfig "github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/apis/config"
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// vgpuUsage is an amount of vGPU resources.
+type vgpuUsage struct {
+	number int64
+	cores  int64
+	memory int64
+}
+
+func (u *vgpuUsage) add(o vgpuUsage) {
+	u.number += o.number
+	u.cores += o.cores
+	u.memory += o.memory
+}
+
+func (u *vgpuUsage) sub(o vgpuUsage) {
+	u.number -= o.number
+	u.cores -= o.cores
+	u.memory -= o.memory
+}
+
+// containerUsage returns the vGPU resources of a container requesting number devices of the cores and memory.
+func containerUsage(number, cores, memory int) vgpuUsage {
+	return vgpuUsage{number: int64(number), cores: int64(number * cores), memory: int64(number * memory)}
+}
+
+// podRequestedUsage returns the vGPU resources requested by a pod holding devices. The usage is
+// counted in the units of the resource requests, like the pending pod, rather than from the
+// allocated devices whose memory depends on the memory factor of the node.
+func podRequestedUsage(pod *v1.Pod) vgpuUsage {
+	var usage vgpuUsage
+	if allocationFailed(pod) {
+		return usage
+	}
+	for i := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[i]
+		if !util.IsVGPURequiredContainer(container) {
+			continue
+		}
+		usage.add(containerUsage(
+			util.GetResourceOfContainer(container, util.VGPUNumberResourceName),
+			util.GetResourceOfContainer(container, util.VGPUCoreResourceName),
+			util.GetResourceOfContainer(container, util.VGPUMemoryResourceName)))
+	}
+	return usage
+}
+
+// requestedUsage returns the vGPU resources requested by the pod.
+func (r *podRequest) requestedUsage() vgpuUsage {
+	var usage vgpuUsage
+	for _, container := range r.containers {
+		usage.add(containerUsage(container.Number, container.Cores, container.Memory))
+	}
+	return usage
+}
+
+// vgpuQuota is the parsed form of pluginconfig.VGPUQuota.
+type vgpuQuota struct {
+	name       string
+	namespaces sets.Set[string]
+	selector   labels.Selector
+	limit      pluginconfig.VGPUQuota
+}
+
+func newVGPUQuotas(quotas []pluginconfig.VGPUQuota) ([]*vgpuQuota, error) {
+	result := make([]*vgpuQuota, 0, len(quotas))
+	for _, quota := range quotas {
+		selector := labels.Everything()
+		if quota.Selector != nil {
+			var err error
+			if selector, err = metav1.LabelSelectorAsSelector(quota.Selector); err != nil {
+				return nil, fmt.Errorf("quota %s: %w", quota.Name, err)
+			}
+		}
+		result = append(result, &vgpuQuota{
+			name:       quota.Name,
+			namespaces: sets.New(quota.Namespaces...),
+			selector:   selector,
+			limit:      quota,
+		})
+	}
+	return result, nil
+}
+
+func (q *vgpuQuota) matches(pod *v1.Pod) bool {
+	if q.namespaces.Len() > 0 && !q.namespaces.Has(pod.Namespace) {
+		return false
+	}
+	return q.selector.Matches(labels.Set(pod.Labels))
+}
+
+// exceeds returns the first resource of the usage exceeding the limit.
+func (u vgpuUsage) exceeds(limit pluginconfig.VGPUResourceList) (string, int64, int64, bool) {
+	switch {
+	case limit.Number != nil && u.number > *limit.Number:
+		return "number", u.number, *limit.Number, true
+	case limit.Cores != nil && u.cores > *limit.Cores:
+		return "cores", u.cores, *limit.Cores, true
+	case limit.Memory != nil && u.memory > *limit.Memory:
+		return "memory", u.memory, *limit.Memory, true
+	}
+	return "", 0, 0, false
+}
+
+// listQuotaPods returns the pods holding devices that may be subject to the quota,
+// including the pods whose devices are only reserved in the assume cache.
+func (p *VGPUSchedulerPlugin) listQuotaPods(namespaces sets.Set[string], selector labels.Selector) ([]*v1.Pod, error) {
+	var pods []*v1.Pod
+	if namespaces.Len() == 0 {
+		list, err := p.podlister.List(selector)
+		if err != nil {
+			return nil, err
+		}
+		pods = list
+	} else {
+		for _, namespace := range sets.List(namespaces) {
+			list, err := p.podlister.Pods(namespace).List(selector)
+			if err != nil {
+				return nil, err
+			}
+			pods = append(pods, list...)
+		}
+	}
+	return p.cache.assumed.Merge(pods), nil
+}
+
+// checkQuotas returns an error when scheduling the pod would exceed one of the quotas it is subject to.
+func (p *VGPUSchedulerPlugin) checkQuotas(pod *v1.Pod, request *podRequest) error {
+	for _, quota := range p.quotas {
+		if !quota.matches(pod) {
+			continue
+		}
+		pods, err := p.listQuotaPods(quota.namespaces, quota.selector)
+		if err != nil {
+			return err
+		}
+		usage := request.requestedUsage()
+		for _, quotaPod := range pods {
+			if quotaPod.UID == pod.UID || !quota.matches(quotaPod) || accountedNodeName(quotaPod) == "" {
+				continue
+			}
+			usage.add(podRequestedUsage(quotaPod))
+		}
+		if resource, used, limit, ok := usage.exceeds(quota.limit.VGPUResourceList); ok {
+			return &quotaExceededError{quota: quota.name, resource: resource, used: used, limit: limit}
+		}
+	}
+	return nil
+}
+
+type quotaExceededError struct {
+	quota    string
+	resource string
+	used     int64
+	limit    int64
+}
+
+func (e *quotaExceededError) Error() string {
+	return fmt.Sprintf("vGPU quota %s exceeded: %s would be %d, limit %d", e.quota, e.resource, e.used, e.limit)
+}
@@ -0,0 +1,150 @@
+package plugin
+
+import (
+	"context"
+
+	pluginconfig "github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/apis/config"
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/utils/ptr"
+)
+
+type eventHandleStub struct {
+	framework.Handle
+	recorder *events.FakeRecorder
+}
+
+func (h *eventHandleStub) EventRecorder() events.EventRecorder {
+	return h.recorder
+}
+
+var _ = Describe("VGPUSchedulerPlugin quotas", func() {
+	var (
+		plugin    *VGPUSchedulerPlugin
+		handle    *eventHandleStub
+		indexer   cache.Indexer
+		ctx       context.Context
+		testState *framework.CycleState
+	)
+
+	newQuotaPod := func(name, namespace string, number int64) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				UID:       uuid.NewUUID(),
+			},
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{{
+					Name: "default",
+					Resources: v1.ResourceRequirements{
+						Limits: v1.ResourceList{
+							util.VGPUNumberResourceName: *resource.NewQuantity(number, resource.DecimalSI),
+							util.VGPUCoreResourceName:   resource.MustParse("50"),
+							util.VGPUMemoryResourceName: resource.MustParse("1024"),
+						},
+					},
+				}},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		testState = framework.NewCycleState()
+		handle = &eventHandleStub{recorder: events.NewFakeRecorder(10)}
+		indexer = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		quotas, err := newVGPUQuotas([]pluginconfig.VGPUQuota{{
+			Name:       "team-a",
+			Namespaces: []string{"team-a"},
+			VGPUResourceList: pluginconfig.VGPUResourceList{
+				Number: ptr.To[int64](2),
+				Memory: ptr.To[int64](4096),
+			},
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		plugin = &VGPUSchedulerPlugin{
+			handle:    handle,
+			podlister: listersv1.NewPodLister(indexer),
+			cache:     newDeviceCache(),
+			quotas:    quotas,
+		}
+		runningPod := newQuotaPod("running-pod", "team-a", 1)
+		runningPod.Spec.NodeName = "test-node"
+		runningPod.Annotations = map[string]string{
+			util.PodVGPURealAllocAnnotation: "default[0_GPU-0_50_1024]",
+		}
+		Expect(indexer.Add(runningPod)).To(Succeed())
+	})
+
+	It("should admit pods within the quota", func() {
+		_, status := plugin.PreFilter(ctx, testState, newQuotaPod("test-pod", "team-a", 1))
+		Expect(status.IsSuccess()).To(BeTrue())
+	})
+
+	It("should not apply the quota to other namespaces", func() {
+		_, status := plugin.PreFilter(ctx, testState, newQuotaPod("test-pod", "team-b", 4))
+		Expect(status.IsSuccess()).To(BeTrue())
+	})
+
+	It("should reject pods exceeding the quota with an event", func() {
+		_, status := plugin.PreFilter(ctx, testState, newQuotaPod("test-pod", "team-a", 2))
+		Expect(status.Code()).To(Equal(framework.UnschedulableAndUnresolvable))
+		Expect(status.Message()).To(ContainSubstring("vGPU quota team-a exceeded: number would be 3, limit 2"))
+		Expect(handle.recorder.Events).To(Receive(ContainSubstring("VGPUQuotaExceeded")))
+	})
+
+	Context("when counting the memory", func() {
+		BeforeEach(func() {
+			quotas, err := newVGPUQuotas([]pluginconfig.VGPUQuota{{
+				Name:             "team-a",
+				Namespaces:       []string{"team-a"},
+				VGPUResourceList: pluginconfig.VGPUResourceList{Memory: ptr.To[int64](3072)},
+			}})
+			Expect(err).NotTo(HaveOccurred())
+			plugin.quotas = quotas
+		})
+
+		It("should count the requested memory regardless of the node memory factor", func() {
+			// The node of the pod has a memory factor of 2, its 1024 requested hold 2048 MiB.
+			scaledPod := newQuotaPod("scaled-pod", "team-a", 1)
+			scaledPod.Spec.NodeName = "scaled-node"
+			scaledPod.Annotations = map[string]string{util.PodVGPURealAllocAnnotation: "default[0_GPU-0_50_2048]"}
+			Expect(indexer.Add(scaledPod)).To(Succeed())
+
+			_, status := plugin.PreFilter(ctx, testState, newQuotaPod("test-pod", "team-a", 1))
+			Expect(status.IsSuccess()).To(BeTrue())
+			_, status = plugin.PreFilter(ctx, framework.NewCycleState(), newQuotaPod("test-pod", "team-a", 2))
+			Expect(status.Message()).To(ContainSubstring("vGPU quota team-a exceeded: memory would be 4096, limit 3072"))
+		})
+
+		It("should count no memory for the pods requesting the number only", func() {
+			numberOnlyPod := newQuotaPod("number-only-pod", "team-a", 1)
+			delete(numberOnlyPod.Spec.Containers[0].Resources.Limits, util.VGPUMemoryResourceName)
+			numberOnlyPod.Spec.NodeName = "test-node"
+			numberOnlyPod.Annotations = map[string]string{util.PodVGPURealAllocAnnotation: "default[1_GPU-1_50_10240]"}
+			Expect(indexer.Add(numberOnlyPod)).To(Succeed())
+
+			testPod := newQuotaPod("test-pod", "team-a", 2)
+			delete(testPod.Spec.Containers[0].Resources.Limits, util.VGPUMemoryResourceName)
+			_, status := plugin.PreFilter(ctx, testState, testPod)
+			Expect(status.IsSuccess()).To(BeTrue())
+		})
+	})
+
+	It("should count the devices reserved in the assume cache", func() {
+		assumedPod := newQuotaPod("assumed-pod", "team-a", 1)
+		plugin.cache.assumed.Assume(assumedPod, "test-node", "default[1_GPU-1_50_1024]")
+		_, status := plugin.PreFilter(ctx, testState, newQuotaPod("test-pod", "team-a", 1))
+		Expect(status.Code()).To(Equal(framework.UnschedulableAndUnresolvable))
+	})
+})
@@ -0,0 +1,42 @@
+package plugin
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+var _ framework.ReservePlugin = &VGPUSchedulerPlugin{}
+
+// Reserve records the devices pre-allocated on the selected node in the assume cache,
+// so that the following scheduling cycles will not allocate the same devices again.
+func (p *VGPUSchedulerPlugin) Reserve(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) *framework.Status {
+	if !p.isVGPUResourcePod(state, pod) {
+		return framework.NewStatus(framework.Success, "")
+	}
+	logger := klog.FromContext(ctx)
+	data, err := state.Read(p.preAllocateDeviceKey(nodeName))
+	if err != nil {
+		errMsg := "getting pre allocated devices for node failed"
+		logger.Error(err, errMsg, "pod", klog.KObj(pod), "node", nodeName)
+		return framework.NewStatus(framework.Error, errMsg)
+	}
+	p.cache.assumed.Assume(pod, nodeName, string(data.(preAllocateDevice)))
+	p.observeSelectedNodeScore(state, p.getPodRequest(state, pod), nodeName)
+	logger.V(4).Info("Reserved pre allocated devices", "pod", klog.KObj(pod), "node", nodeName)
+	return framework.NewStatus(framework.Success, "")
+}
+
+// Unreserve reverts the metadata patched in PreBind, releases the devices pre-allocated
+// in the Reserve phase, and rejects the rest of the pod group the pod belongs to.
+func (p *VGPUSchedulerPlugin) Unreserve(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) {
+	p.rollbackBind(ctx, state, pod, nodeName)
+	p.rejectPodGroup(ctx, pod)
+	if !p.isVGPUResourcePod(state, pod) {
+		return
+	}
+	p.cache.assumed.Forget(pod.UID)
+	klog.FromContext(ctx).V(4).Info("Unreserved pre allocated devices", "pod", klog.KObj(pod), "node", nodeName)
+}
@@ -0,0 +1,83 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+var _ = Describe("VGPUSchedulerPlugin Reserve", func() {
+	var (
+		plugin      *VGPUSchedulerPlugin
+		ctx         context.Context
+		testPod     *v1.Pod
+		testState   *framework.CycleState
+		nodeName    = "test-node"
+		preAllocStr = "default[0_GPU-xxxx_0_2048]"
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		testPod = &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-pod",
+				Namespace: "default",
+				UID:       uuid.NewUUID(),
+			},
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{{
+					Name: "default",
+					Resources: v1.ResourceRequirements{
+						Limits: v1.ResourceList{
+							util.VGPUNumberResourceName: resource.MustParse("1"),
+						},
+					},
+				}},
+			},
+		}
+		testState = framework.NewCycleState()
+		plugin = &VGPUSchedulerPlugin{cache: newDeviceCache()}
+	})
+
+	Context("when reserving vGPU pod", func() {
+		BeforeEach(func() {
+			testState.Write(plugin.preAllocateDeviceKey(nodeName), preAllocateDevice(preAllocStr))
+		})
+		It("should assume the pre allocated devices until unreserved", func() {
+			status := plugin.Reserve(ctx, testState, testPod, nodeName)
+			Expect(status.IsSuccess()).To(BeTrue())
+
+			pods := plugin.cache.assumed.Merge([]*v1.Pod{testPod})
+			Expect(pods).To(HaveLen(1))
+			Expect(pods[0].Annotations[util.PodVGPUPreAllocAnnotation]).To(Equal(preAllocStr))
+			Expect(pods[0].Annotations[util.PodPredicateNodeAnnotation]).To(Equal(nodeName))
+
+			plugin.Unreserve(ctx, testState, testPod, nodeName)
+			Expect(plugin.cache.assumed.IsAssumed(testPod.UID)).To(BeFalse())
+		})
+		It("should release the assumed pod when the informer observes the allocation", func() {
+			Expect(plugin.Reserve(ctx, testState, testPod, nodeName).IsSuccess()).To(BeTrue())
+			Expect(plugin.cache.assumed.IsAssumed(testPod.UID)).To(BeTrue())
+
+			updatedPod := testPod.DeepCopy()
+			util.InsertAnnotation(updatedPod, util.PodVGPUPreAllocAnnotation, preAllocStr)
+			plugin.cache.assumed.observe(updatedPod)
+			Expect(plugin.cache.assumed.IsAssumed(testPod.UID)).To(BeFalse())
+		})
+	})
+
+	Context("when state read fails", func() {
+		It("should return error status", func() {
+			status := plugin.Reserve(ctx, testState, testPod, nodeName)
+			Expect(status.Code()).To(Equal(framework.Error))
+			Expect(plugin.cache.assumed.IsAssumed(testPod.UID)).To(BeFalse())
+		})
+	})
+})
@@ -2,10 +2,9 @@ package plugin
 
 import (
 	"context"
-	"strings"
 
+	"github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/metrics"
 	"github.com/coldzerofear/vgpu-manager/pkg/device"
-	"github.com/coldzerofear/vgpu-manager/pkg/device/allocator"
 	"github.com/coldzerofear/vgpu-manager/pkg/util"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
@@ -14,10 +13,47 @@ import (
 
 var _ framework.ScorePlugin = &VGPUSchedulerPlugin{}
 
+// neutralNodeScore is the score given to every node when no node scheduling policy is used.
+const neutralNodeScore = 50
+
+const nodeScoresKey framework.StateKey = "NodeScores"
+
+// nodeScores holds the normalized score of each node, the score of the
+// node selected in the Reserve phase is recorded in the metrics.
+type nodeScores map[string]int64
+
+func (s nodeScores) Clone() framework.StateData {
+	return s
+}
+
+// observeSelectedNodeScore records the normalized score of the node selected for the pod.
+func (p *VGPUSchedulerPlugin) observeSelectedNodeScore(state *framework.CycleState, request *podRequest, nodeName string) {
+	data, err := state.Read(nodeScoresKey)
+	if err != nil {
+		return
+	}
+	score, ok := data.(nodeScores)[nodeName]
+	if !ok {
+		return
+	}
+	policy, _ := p.nodeScorer(request)
+	metrics.SelectedNodeScore.WithLabelValues(policy).Observe(float64(score))
+}
+
+// nodeScorer returns the node scheduling policy of the pod and its scorer, the pods
+// requesting an unknown policy are scored with the none policy.
+func (p *VGPUSchedulerPlugin) nodeScorer(request *podRequest) (string, NodeScorer) {
+	if scorer, ok := p.nodeScorers[request.nodePolicy]; ok {
+		return request.nodePolicy, scorer
+	}
+	return string(util.NonePolicy), NodeScorerFunc(scoreNeutral)
+}
+
 func (p *VGPUSchedulerPlugin) Score(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) (score int64, status *framework.Status) {
 	logger := klog.FromContext(ctx)
 	score = framework.MinNodeScore
-	if !p.isVGPUResourcePod(state, pod) {
+	request := p.getPodRequest(state, pod)
+	if request.totalNumber == 0 {
 		logger.Info("pod did not request vGPU, skipping node Score", "pod", klog.KObj(pod), "plugin", "Score")
 		return score, framework.NewStatus(framework.Success, "")
 	}
@@ -33,27 +69,17 @@ func (p *VGPUSchedulerPlugin) Score(ctx context.Context, state *framework.CycleS
 		return score, framework.NewStatus(framework.Error, err.Error())
 	}
 	// Sort nodes according to node scheduling strategy.
-	nodePolicy, _ := util.HasAnnotation(pod, util.NodeSchedulerPolicyAnnotation)
-	switch strings.ToLower(nodePolicy) {
-	case string(util.BinpackPolicy):
-		klog.V(4).Infof("Pod <%s> use <%s> node scheduling policy", klog.KObj(pod), nodePolicy)
-		score = int64(allocator.GetBinpackNodeScore(devNodeInfo, float64(framework.MaxNodeScore)))
-	case string(util.SpreadPolicy):
-		klog.V(4).Infof("Pod <%s> use <%s> node scheduling policy", klog.KObj(pod), nodePolicy)
-		score = int64(allocator.GetSpreadNodeScore(devNodeInfo, float64(framework.MaxNodeScore)))
-	default:
-		klog.V(4).Infof("Pod <%s> no node scheduling policy", klog.KObj(pod))
-		score = 50
-	}
-	score = addGPUTopologyScore(pod, devNodeInfo, score)
+	nodePolicy, scorer := p.nodeScorer(request)
+	klog.V(4).Infof("Pod <%s> use <%s> node scheduling policy", klog.KObj(pod), nodePolicy)
+	score = clampScore(scorer.Score(pod, devNodeInfo))
+	score = addGPUTopologyScore(pod, request, devNodeInfo, score, p.topologyBonusPercent)
+	score = p.applyFlappingPenalty(nodeName, score)
 	logger.Info("Calculate node score", "score", score, "node", nodeName)
 	return score, framework.NewStatus(framework.Success, "")
 }
 
-func addGPUTopologyScore(pod *v1.Pod, nodeInfo *device.NodeInfo, score int64) int64 {
-	const topologyAdjustmentPercent = 10
-	topoMode, ok := util.HasAnnotation(pod, util.DeviceTopologyModeAnnotation)
-	if ok && strings.EqualFold(topoMode, string(util.LinkTopology)) {
+func addGPUTopologyScore(pod *v1.Pod, request *podRequest, nodeInfo *device.NodeInfo, score, topologyAdjustmentPercent int64) int64 {
+	if request.topologyMode == string(util.LinkTopology) {
 		adjustment := (score*topologyAdjustmentPercent + 99) / 100
 		if nodeInfo.HasGPUTopology() {
 			score += adjustment
@@ -65,14 +91,15 @@ func addGPUTopologyScore(pod *v1.Pod, nodeInfo *device.NodeInfo, score int64) in
 				float64(topologyAdjustmentPercent), adjustment, nodeInfo.GetName(), klog.KObj(pod))
 		}
 	}
-	return clampScore(score)
+	// The bonus may take the score above MaxNodeScore, NormalizeScore brings it back into range.
+	return max(score, framework.MinNodeScore)
 }
 
 func clampScore(score int64) int64 {
 	switch {
-	case score > framework.MaxTotalScore:
-		klog.V(5).Infof("Clamping score from %d to max %d", score, framework.MaxTotalScore)
-		return framework.MaxTotalScore
+	case score > framework.MaxNodeScore:
+		klog.V(5).Infof("Clamping score from %d to max %d", score, framework.MaxNodeScore)
+		return framework.MaxNodeScore
 	case score < framework.MinNodeScore:
 		klog.V(5).Infof("Clamping score from %d to min %d", score, framework.MinNodeScore)
 		return framework.MinNodeScore
@@ -83,5 +110,25 @@ func clampScore(score int64) int64 {
 
 // ScoreExtensions returns a ScoreExtensions interface if it implements one, or nil if does not.
 func (p *VGPUSchedulerPlugin) ScoreExtensions() framework.ScoreExtensions {
-	return nil
+	return p
+}
+
+// NormalizeScore scales the node scores down proportionally when the topology bonus takes
+// the highest of them above MaxNodeScore, so all scores end up in [MinNodeScore, MaxNodeScore].
+func (p *VGPUSchedulerPlugin) NormalizeScore(ctx context.Context, state *framework.CycleState, pod *v1.Pod, scores framework.NodeScoreList) *framework.Status {
+	var highestScore int64
+	normalizedScores := make(nodeScores, len(scores))
+	for i := range scores {
+		highestScore = max(highestScore, scores[i].Score)
+	}
+	for i := range scores {
+		if highestScore > framework.MaxNodeScore {
+			scores[i].Score = scores[i].Score * framework.MaxNodeScore / highestScore
+		}
+		scores[i].Score = clampScore(scores[i].Score)
+		normalizedScores[scores[i].Name] = scores[i].Score
+	}
+	state.Write(nodeScoresKey, normalizedScores)
+	klog.FromContext(ctx).V(5).Info("Normalized node scores", "pod", klog.KObj(pod), "highestScore", highestScore)
+	return framework.NewStatus(framework.Success, "")
 }
@@ -0,0 +1,51 @@
+package plugin
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+var _ = Describe("VGPUSchedulerPlugin NormalizeScore", func() {
+	var (
+		plugin    *VGPUSchedulerPlugin
+		ctx       context.Context
+		testState *framework.CycleState
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		testState = framework.NewCycleState()
+		plugin = &VGPUSchedulerPlugin{}
+	})
+
+	Context("when the topology bonus exceeds the max node score", func() {
+		It("should scale the scores into range", func() {
+			scores := framework.NodeScoreList{
+				{Name: "node1", Score: 110},
+				{Name: "node2", Score: 55},
+				{Name: "node3", Score: 0},
+			}
+			status := plugin.NormalizeScore(ctx, testState, nil, scores)
+			Expect(status.IsSuccess()).To(BeTrue())
+			Expect(scores[0].Score).To(Equal(framework.MaxNodeScore))
+			Expect(scores[1].Score).To(Equal(int64(50)))
+			Expect(scores[2].Score).To(Equal(framework.MinNodeScore))
+		})
+	})
+
+	Context("when all scores are in range", func() {
+		It("should keep the scores unchanged", func() {
+			scores := framework.NodeScoreList{
+				{Name: "node1", Score: 80},
+				{Name: "node2", Score: 20},
+			}
+			status := plugin.NormalizeScore(ctx, testState, nil, scores)
+			Expect(status.IsSuccess()).To(BeTrue())
+			Expect(scores[0].Score).To(Equal(int64(80)))
+			Expect(scores[1].Score).To(Equal(int64(20)))
+		})
+	})
+})
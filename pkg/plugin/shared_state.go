@@ -0,0 +1,123 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	pluginconfig "github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/apis/config"
+	"k8s.io/client-go/informers"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// sharedState is shared by the plugin instances of the profiles, which schedule pods on the same nodes.
+type sharedState struct {
+	config        sharedConfig
+	cache         *deviceCache
+	throttle      *bindThrottle
+	health        *gpuHealthTracker
+	elasticQuotas *elasticQuotaManager
+}
+
+// sharedConfig are the plugin args that must be the same in all the profiles.
+type sharedConfig struct {
+	bindThrottleInterval  time.Duration
+	gpuFlappingWindow     time.Duration
+	allocationTimeout     time.Duration
+	deleteTimedOutPods    bool
+	debugBindAddress      string
+	elasticQuotaConfigMap string
+}
+
+func newSharedConfig(args *pluginconfig.VGPUSchedulerPluginArgs) sharedConfig {
+	return sharedConfig{
+		bindThrottleInterval:  args.BindThrottleInterval.Duration,
+		gpuFlappingWindow:     args.GPUFlappingWindow.Duration,
+		allocationTimeout:     args.AllocationTimeout.Duration,
+		deleteTimedOutPods:    args.DeleteTimedOutPods,
+		debugBindAddress:      args.DebugBindAddress,
+		elasticQuotaConfigMap: args.ElasticQuotaConfigMap,
+	}
+}
+
+var (
+	sharedStatesMutex sync.Mutex
+	// sharedStates are keyed by the informer factory shared by the profiles of a scheduler.
+	sharedStates = map[informers.SharedInformerFactory]*sharedState{}
+)
+
+func getOrCreateSharedState(ctx context.Context, handle framework.Handle, args *pluginconfig.VGPUSchedulerPluginArgs) (*sharedState, error) {
+	config := newSharedConfig(args)
+	informerFactory := handle.SharedInformerFactory()
+	sharedStatesMutex.Lock()
+	defer sharedStatesMutex.Unlock()
+	if state, ok := sharedStates[informerFactory]; ok {
+		if state.config != config {
+			return nil, fmt.Errorf("bindThrottleInterval, gpuFlappingWindow, allocationTimeout, deleteTimedOutPods, " +
+				"debugBindAddress and elasticQuotaConfigMap must be the same in all the profiles")
+		}
+		return state, nil
+	}
+	state, err := newSharedState(ctx, handle, config)
+	if err != nil {
+		return nil, err
+	}
+	sharedStates[informerFactory] = state
+	go func() {
+		<-ctx.Done()
+		sharedStatesMutex.Lock()
+		defer sharedStatesMutex.Unlock()
+		delete(sharedStates, informerFactory)
+	}()
+	return state, nil
+}
+
+func newSharedState(ctx context.Context, handle framework.Handle, config sharedConfig) (*sharedState, error) {
+	informerFactory := handle.SharedInformerFactory()
+	podInformer := informerFactory.Core().V1().Pods().Informer()
+	nodeInformer := informerFactory.Core().V1().Nodes().Informer()
+	state := &sharedState{
+		config:   config,
+		cache:    newDeviceCache(),
+		throttle: newBindThrottle(config.bindThrottleInterval, informerFactory.Core().V1().Pods().Lister()),
+		health:   newGPUHealthTracker(config.gpuFlappingWindow),
+	}
+	if _, err := podInformer.AddEventHandler(state.cache.podEventHandler()); err != nil {
+		return nil, err
+	}
+	if _, err := nodeInformer.AddEventHandler(state.cache.nodeEventHandler()); err != nil {
+		return nil, err
+	}
+	if _, err := nodeInformer.AddEventHandler(state.health.nodeEventHandler()); err != nil {
+		return nil, err
+	}
+	if _, err := podInformer.AddEventHandler(state.throttle.podEventHandler()); err != nil {
+		return nil, err
+	}
+	if _, err := nodeInformer.AddEventHandler(state.throttle.nodeEventHandler()); err != nil {
+		return nil, err
+	}
+	if len(config.elasticQuotaConfigMap) > 0 {
+		state.elasticQuotas = newElasticQuotaManager()
+		registration, err := informerFactory.Core().V1().ConfigMaps().Informer().AddEventHandler(
+			state.elasticQuotas.configMapEventHandler(config.elasticQuotaConfigMap))
+		if err != nil {
+			return nil, err
+		}
+		state.elasticQuotas.synced = registration.HasSynced
+	}
+	// The debug server runs on every replica, each serving its own view of the devices.
+	if len(config.debugBindAddress) > 0 {
+		startDebugServer(ctx, config.debugBindAddress, state.cache.debugHandler())
+	}
+	// The watchdog patches and deletes pods, only the leading replica runs it.
+	if config.allocationTimeout > 0 {
+		watchdog := newAllocationWatchdog(handle.ClientSet(), informerFactory.Core().V1().Pods().Lister(),
+			handle.EventRecorder(), config.allocationTimeout, config.deleteTimedOutPods)
+		if err := runAsLeader(ctx, handle.ClientSet(), leaseNamespace(), allocationWatchdogLease, watchdog.start); err != nil {
+			return nil, err
+		}
+	}
+	return state, nil
+}
@@ -0,0 +1,115 @@
+package plugin
+
+import (
+	"context"
+	"time"
+
+	pluginconfig "github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/apis/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/utils/ptr"
+)
+
+type informerHandleStub struct {
+	frameworkHandleStub
+	informerFactory informers.SharedInformerFactory
+}
+
+func (h *informerHandleStub) SharedInformerFactory() informers.SharedInformerFactory {
+	return h.informerFactory
+}
+
+var _ = Describe("VGPUSchedulerPlugin shared state", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		args   *pluginconfig.VGPUSchedulerPluginArgs
+	)
+
+	newHandle := func(objects ...runtime.Object) framework.Handle {
+		clientSet := fake.NewClientset(objects...)
+		return &informerHandleStub{
+			frameworkHandleStub: frameworkHandleStub{clientSet: clientSet},
+			informerFactory:     informers.NewSharedInformerFactory(clientSet, 0),
+		}
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		args = &pluginconfig.VGPUSchedulerPluginArgs{AllocationTimeout: &metav1.Duration{}}
+		pluginconfig.SetDefaults(args)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("should share the state between the profiles of a scheduler", func() {
+		handle := newHandle()
+		state, err := getOrCreateSharedState(ctx, handle, args)
+		Expect(err).NotTo(HaveOccurred())
+		profileArgs := *args
+		profileArgs.DefaultNodePolicy = ptr.To("spread")
+		Expect(getOrCreateSharedState(ctx, handle, &profileArgs)).To(BeIdenticalTo(state))
+
+		otherState, err := getOrCreateSharedState(ctx, newHandle(), args)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(otherState).NotTo(BeIdenticalTo(state))
+	})
+
+	It("should reject profiles configuring the shared state differently", func() {
+		handle := newHandle()
+		_, err := getOrCreateSharedState(ctx, handle, args)
+		Expect(err).NotTo(HaveOccurred())
+		args.BindThrottleInterval = &metav1.Duration{Duration: time.Second}
+		_, err = getOrCreateSharedState(ctx, handle, args)
+		Expect(err).To(MatchError(ContainSubstring("must be the same in all the profiles")))
+	})
+
+	It("should drop the state once the scheduler stops", func() {
+		handle := newHandle()
+		_, err := getOrCreateSharedState(ctx, handle, args)
+		Expect(err).NotTo(HaveOccurred())
+		cancel()
+		Eventually(func() bool {
+			sharedStatesMutex.Lock()
+			defer sharedStatesMutex.Unlock()
+			_, ok := sharedStates[handle.SharedInformerFactory()]
+			return ok
+		}).Should(BeFalse())
+	})
+
+	It("should read the elastic quotas from the shared informer factory", func() {
+		newConfigMap := func(namespace, name string) *v1.ConfigMap {
+			return &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+				Data: map[string]string{elasticQuotasConfigMapKey: `
+- name: team-a
+  namespaces: [team-a]
+  max: {number: 4}
+`},
+			}
+		}
+		handle := newHandle(newConfigMap("kube-system", "vgpu-elastic-quotas"), newConfigMap("default", "vgpu-elastic-quotas"))
+		args.ElasticQuotaConfigMap = "kube-system/vgpu-elastic-quotas"
+		state, err := getOrCreateSharedState(ctx, handle, args)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state.elasticQuotas.waitForSync(ctx, handle.SharedInformerFactory())).To(Succeed())
+		Expect(state.elasticQuotas.quotaOf("team-a")).NotTo(BeNil())
+
+		Expect(handle.ClientSet().CoreV1().ConfigMaps("default").Delete(ctx, "vgpu-elastic-quotas", metav1.DeleteOptions{})).To(Succeed())
+		Consistently(func() *elasticQuota {
+			return state.elasticQuotas.quotaOf("team-a")
+		}, 200*time.Millisecond).ShouldNot(BeNil())
+		Expect(handle.ClientSet().CoreV1().ConfigMaps("kube-system").Delete(ctx, "vgpu-elastic-quotas", metav1.DeleteOptions{})).To(Succeed())
+		Eventually(func() *elasticQuota {
+			return state.elasticQuotas.quotaOf("team-a")
+		}).Should(BeNil())
+	})
+})
@@ -0,0 +1,115 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	pluginconfig "github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/apis/config"
+	"github.com/coldzerofear/vgpu-manager/pkg/device"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+// NewSimulateCommand creates the command simulating the scheduling of pending vGPU pods offline.
+func NewSimulateCommand() *cobra.Command {
+	var (
+		files          []string
+		pluginArgsFile string
+	)
+	cmd := &cobra.Command{
+		Use:   "simulate -f FILENAME [-f FILENAME...]",
+		Short: "Simulate the scheduling of pending vGPU pods without a cluster",
+		Long: `Simulate loads the nodes and pods of a cluster from YAML or JSON files (e.g. the output of
+'kubectl get nodes,pods -A -o yaml'), schedules the pending vGPU pods one after another with the
+PreFilter, Filter, Score, Reserve and PreBind logic of the plugin, and prints the node and the GPUs
+each pod would get. Pods with a node occupy the devices recorded in their annotations.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			args, err := loadPluginArgs(pluginArgsFile)
+			if err != nil {
+				return err
+			}
+			objects, err := LoadObjects(files, cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+			ctx, cancel := context.WithCancel(cmd.Context())
+			defer cancel()
+			simulator, err := New(ctx, objects, args)
+			if err != nil {
+				return err
+			}
+			results, err := simulator.Run(ctx)
+			if err != nil {
+				return err
+			}
+			return PrintResults(cmd.OutOrStdout(), results)
+		},
+	}
+	cmd.Flags().StringSliceVarP(&files, "filename", "f", nil, "Files holding the nodes and pods of the cluster, - reads the standard input.")
+	cmd.Flags().StringVar(&pluginArgsFile, "plugin-args", "", "File holding the VGPUSchedulerPlugin args, as in the pluginConfig of the scheduler configuration.")
+	_ = cmd.MarkFlagRequired("filename")
+	// The scheduler command prints its own flags in the usage and help of its sub commands.
+	cmd.SetUsageFunc(func(cmd *cobra.Command) error {
+		printUsage(cmd.OutOrStderr(), cmd)
+		return nil
+	})
+	cmd.SetHelpFunc(func(cmd *cobra.Command, _ []string) {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s\n\n", cmd.Long)
+		printUsage(cmd.OutOrStdout(), cmd)
+	})
+	return cmd
+}
+
+func printUsage(w io.Writer, cmd *cobra.Command) {
+	fmt.Fprintf(w, "Usage:\n  %s\n\nFlags:\n%s", cmd.UseLine(), cmd.LocalFlags().FlagUsages())
+	if cmd.HasAvailableInheritedFlags() {
+		fmt.Fprintf(w, "\nGlobal Flags:\n%s", cmd.InheritedFlags().FlagUsages())
+	}
+}
+
+func loadPluginArgs(path string) (*pluginconfig.VGPUSchedulerPluginArgs, error) {
+	args := &pluginconfig.VGPUSchedulerPluginArgs{}
+	if len(path) == 0 {
+		return args, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err = yaml.UnmarshalStrict(data, args); err != nil {
+		return nil, fmt.Errorf("loading plugin args %s: %w", path, err)
+	}
+	return args, nil
+}
+
+// PrintResults writes the results as a table.
+func PrintResults(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tPOD\tNODE\tGPUS")
+	for _, result := range results {
+		node, gpus := result.Node, formatDevices(result.Devices)
+		if len(node) == 0 {
+			node, gpus = "<none>", result.Reason
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", result.Pod.Namespace, result.Pod.Name, node, gpus)
+	}
+	return tw.Flush()
+}
+
+// formatDevices formats the devices of each container like container[0:GPU-xxx:50c/2048Mi].
+func formatDevices(podDevices device.PodDevices) string {
+	containers := make([]string, 0, len(podDevices))
+	for _, container := range podDevices {
+		devices := make([]string, 0, len(container.Devices))
+		for _, dev := range container.Devices {
+			devices = append(devices, fmt.Sprintf("%d:%s:%dc/%dMi", dev.Id, dev.Uuid, dev.Cores, dev.Memory))
+		}
+		containers = append(containers, fmt.Sprintf("%s[%s]", container.Name, strings.Join(devices, ",")))
+	}
+	return strings.Join(containers, " ")
+}
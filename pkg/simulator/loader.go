@@ -0,0 +1,88 @@
+package simulator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+)
+
+// LoadObjects reads the nodes, pods and config maps of the files, "-" reads the standard input.
+// A file holds YAML or JSON documents, or a List as dumped by `kubectl get -o yaml`.
+func LoadObjects(paths []string, stdin io.Reader) ([]runtime.Object, error) {
+	var objects []runtime.Object
+	for _, path := range paths {
+		reader := stdin
+		if path != "-" {
+			file, err := os.Open(path)
+			if err != nil {
+				return nil, err
+			}
+			defer file.Close()
+			reader = file
+		}
+		objs, err := decodeObjects(reader)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s: %w", path, err)
+		}
+		objects = append(objects, objs...)
+	}
+	return objects, nil
+}
+
+func decodeObjects(reader io.Reader) ([]runtime.Object, error) {
+	var objects []runtime.Object
+	decoder := utilyaml.NewYAMLOrJSONDecoder(reader, 4096)
+	for {
+		var raw runtime.RawExtension
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+			return nil, err
+		}
+		if data := bytes.TrimSpace(raw.Raw); len(data) == 0 || string(data) == "null" {
+			continue
+		}
+		objs, err := decodeObject(raw.Raw)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, objs...)
+	}
+}
+
+func decodeObject(data []byte) ([]runtime.Object, error) {
+	obj, gvk, err := scheme.Codecs.UniversalDeserializer().Decode(data, nil, nil)
+	if err != nil {
+		if runtime.IsNotRegisteredError(err) {
+			klog.V(2).InfoS("Ignoring object of unknown kind", "err", err)
+			return nil, nil
+		}
+		return nil, err
+	}
+	switch o := obj.(type) {
+	case *v1.List:
+		var objects []runtime.Object
+		for _, item := range o.Items {
+			objs, err := decodeObject(item.Raw)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, objs...)
+		}
+		return objects, nil
+	case *v1.Node, *v1.Pod, *v1.ConfigMap:
+		return []runtime.Object{obj}, nil
+	default:
+		klog.V(2).InfoS("Ignoring object not used by the simulation", "kind", gvk.Kind)
+		return nil, nil
+	}
+}
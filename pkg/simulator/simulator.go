@@ -0,0 +1,282 @@
+package simulator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	pluginconfig "github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/apis/config"
+	"github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/plugin"
+	"github.com/coldzerofear/vgpu-manager/pkg/device"
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/events"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+	"k8s.io/kubernetes/pkg/scheduler/apis/config"
+	internalcache "k8s.io/kubernetes/pkg/scheduler/backend/cache"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/defaultbinder"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/queuesort"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	schedulermetrics "k8s.io/kubernetes/pkg/scheduler/metrics"
+)
+
+const schedulerName = "vgpu-simulator"
+
+// Result is the outcome of scheduling a pending pod.
+type Result struct {
+	Pod *v1.Pod
+	// Node is the node the pod would be bound to, empty when the pod is unschedulable.
+	Node string
+	// Devices are the GPUs the pod would get on the node.
+	Devices device.PodDevices
+	// Reason explains why the pod is unschedulable.
+	Reason string
+}
+
+// Simulator schedules the pending vGPU pods of a cluster dump one after another, running the
+// extension points of VGPUSchedulerPlugin against an in-memory cluster. The pods scheduled
+// earlier occupy their devices for the pods scheduled later, as in a live cluster.
+type Simulator struct {
+	client    *fake.Clientset
+	framework framework.Framework
+	snapshot  *internalcache.Snapshot
+	pending   []*v1.Pod
+}
+
+// New builds the in-memory cluster from the objects and instantiates the plugin with the args.
+// The pods without node are the pending pods, the others occupy the devices of their node.
+func New(ctx context.Context, objects []runtime.Object, args *pluginconfig.VGPUSchedulerPluginArgs) (*Simulator, error) {
+	var (
+		nodes        []*v1.Node
+		existingPods []*v1.Pod
+		pending      []*v1.Pod
+	)
+	for _, obj := range objects {
+		switch o := obj.(type) {
+		case *v1.Node:
+			nodes = append(nodes, o)
+		case *v1.Pod:
+			// The plugin tracks pods by UID, which hand written manifests usually lack.
+			if len(o.UID) == 0 {
+				o.UID = uuid.NewUUID()
+			}
+			switch {
+			case util.PodIsTerminated(o):
+			case o.Spec.NodeName != "":
+				existingPods = append(existingPods, o)
+			case util.IsVGPUResourcePod(o):
+				pending = append(pending, o)
+			}
+		}
+	}
+	// Pending pods are scheduled in the order of the scheduling queue, by priority.
+	slices.SortStableFunc(pending, func(a, b *v1.Pod) int {
+		return int(corev1helpers.PodPriority(b)) - int(corev1helpers.PodPriority(a))
+	})
+
+	client := fake.NewClientset(objects...)
+	client.PrependReactor("create", "pods", bindPod(client))
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	snapshot := internalcache.NewSnapshot(existingPods, nodes)
+
+	if args == nil {
+		args = &pluginconfig.VGPUSchedulerPluginArgs{}
+	}
+	if args.BindThrottleInterval == nil {
+		// There is no device plugin picking up the pods.
+		args.BindThrottleInterval = &metav1.Duration{}
+	}
+	// The watchdog would mark failed the allocating pods of the input, the simulation must not change them.
+	args.AllocationTimeout = &metav1.Duration{}
+	rawArgs, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	vgpuPlugins := []config.Plugin{{Name: plugin.Name}}
+	profile := &config.KubeSchedulerProfile{
+		SchedulerName: schedulerName,
+		Plugins: &config.Plugins{
+			QueueSort: config.PluginSet{Enabled: []config.Plugin{{Name: queuesort.Name}}},
+			PreFilter: config.PluginSet{Enabled: vgpuPlugins},
+			Filter:    config.PluginSet{Enabled: vgpuPlugins},
+			PreScore:  config.PluginSet{Enabled: vgpuPlugins},
+			Score:     config.PluginSet{Enabled: []config.Plugin{{Name: plugin.Name, Weight: 1}}},
+			Reserve:   config.PluginSet{Enabled: vgpuPlugins},
+			PreBind:   config.PluginSet{Enabled: vgpuPlugins},
+			Bind:      config.PluginSet{Enabled: []config.Plugin{{Name: defaultbinder.Name}}},
+			PostBind:  config.PluginSet{Enabled: vgpuPlugins},
+		},
+		PluginConfig: []config.PluginConfig{{
+			Name: plugin.Name,
+			Args: &runtime.Unknown{Raw: rawArgs, ContentType: runtime.ContentTypeJSON},
+		}},
+	}
+	// The framework records the scheduler metrics.
+	schedulermetrics.Register()
+	registry := frameworkruntime.Registry{
+		queuesort.Name:     queuesort.New,
+		defaultbinder.Name: defaultbinder.New,
+		plugin.Name:        plugin.New,
+	}
+	fwk, err := frameworkruntime.NewFramework(ctx, registry, profile,
+		frameworkruntime.WithClientSet(client),
+		frameworkruntime.WithInformerFactory(informerFactory),
+		frameworkruntime.WithSnapshotSharedLister(snapshot),
+		frameworkruntime.WithEventRecorder(&events.FakeRecorder{}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	informerFactory.Start(ctx.Done())
+	for informer, synced := range informerFactory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return nil, fmt.Errorf("syncing %v informer failed", informer)
+		}
+	}
+	return &Simulator{
+		client:    client,
+		framework: fwk,
+		snapshot:  snapshot,
+		pending:   pending,
+	}, nil
+}
+
+// bindPod sets the node of the pods bound through the fake client.
+func bindPod(client *fake.Clientset) clienttesting.ReactionFunc {
+	return func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "binding" {
+			return false, nil, nil
+		}
+		binding := action.(clienttesting.CreateAction).GetObject().(*v1.Binding)
+		obj, err := client.Tracker().Get(v1.SchemeGroupVersion.WithResource("pods"), binding.Namespace, binding.Name)
+		if err != nil {
+			return true, nil, err
+		}
+		pod := obj.(*v1.Pod).DeepCopy()
+		pod.Spec.NodeName = binding.Target.Name
+		return true, binding, client.Tracker().Update(v1.SchemeGroupVersion.WithResource("pods"), pod, pod.Namespace)
+	}
+}
+
+// Run schedules the pending pods and returns the result of each of them, in scheduling order.
+func (s *Simulator) Run(ctx context.Context) ([]Result, error) {
+	results := make([]Result, 0, len(s.pending))
+	for _, pod := range s.pending {
+		result, err := s.schedulePod(ctx, pod)
+		if err != nil {
+			return nil, fmt.Errorf("scheduling pod %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (s *Simulator) schedulePod(ctx context.Context, pod *v1.Pod) (Result, error) {
+	result := Result{Pod: pod}
+	state := framework.NewCycleState()
+	preFilterResult, status, _ := s.framework.RunPreFilterPlugins(ctx, state, pod)
+	if !status.IsSuccess() {
+		result.Reason = status.Message()
+		return result, nil
+	}
+	nodeInfos, err := s.snapshot.NodeInfos().List()
+	if err != nil {
+		return result, err
+	}
+	slices.SortFunc(nodeInfos, func(a, b *framework.NodeInfo) int {
+		return strings.Compare(a.Node().Name, b.Node().Name)
+	})
+
+	var feasibleNodes []*framework.NodeInfo
+	reasons := map[string]int{}
+	for _, nodeInfo := range nodeInfos {
+		if !preFilterResult.AllNodes() && !preFilterResult.NodeNames.Has(nodeInfo.Node().Name) {
+			reasons["node is not selected by PreFilter"]++
+			continue
+		}
+		if status := s.framework.RunFilterPlugins(ctx, state, pod, nodeInfo); !status.IsSuccess() {
+			reasons[status.Message()]++
+			continue
+		}
+		feasibleNodes = append(feasibleNodes, nodeInfo)
+	}
+	if len(feasibleNodes) == 0 {
+		result.Reason = fitErrorMessage(len(nodeInfos), reasons)
+		return result, nil
+	}
+
+	nodeName, status := s.selectNode(ctx, state, pod, feasibleNodes)
+	if !status.IsSuccess() {
+		result.Reason = status.Message()
+		return result, nil
+	}
+	if status = s.framework.RunReservePluginsReserve(ctx, state, pod, nodeName); !status.IsSuccess() {
+		s.framework.RunReservePluginsUnreserve(ctx, state, pod, nodeName)
+		result.Reason = status.Message()
+		return result, nil
+	}
+	if status = s.framework.RunPreBindPlugins(ctx, state, pod, nodeName); !status.IsSuccess() {
+		s.framework.RunReservePluginsUnreserve(ctx, state, pod, nodeName)
+		result.Reason = status.Message()
+		return result, nil
+	}
+	if status = s.framework.RunBindPlugins(ctx, state, pod, nodeName); !status.IsSuccess() {
+		s.framework.RunReservePluginsUnreserve(ctx, state, pod, nodeName)
+		result.Reason = status.Message()
+		return result, nil
+	}
+	s.framework.RunPostBindPlugins(ctx, state, pod, nodeName)
+
+	bound, err := s.client.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+	if err != nil {
+		return result, err
+	}
+	nodeInfo, err := s.snapshot.NodeInfos().Get(nodeName)
+	if err != nil {
+		return result, err
+	}
+	nodeInfo.AddPod(bound)
+	result.Node = nodeName
+	result.Devices = device.GetPodAssignDevices(bound)
+	return result, nil
+}
+
+// selectNode returns the feasible node with the highest score, the first one in name order on ties.
+func (s *Simulator) selectNode(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodes []*framework.NodeInfo) (string, *framework.Status) {
+	if len(nodes) == 1 {
+		return nodes[0].Node().Name, nil
+	}
+	if status := s.framework.RunPreScorePlugins(ctx, state, pod, nodes); !status.IsSuccess() {
+		return "", status
+	}
+	scores, status := s.framework.RunScorePlugins(ctx, state, pod, nodes)
+	if !status.IsSuccess() {
+		return "", status
+	}
+	selected := scores[0]
+	for _, score := range scores[1:] {
+		if score.TotalScore > selected.TotalScore {
+			selected = score
+		}
+	}
+	return selected.Name, nil
+}
+
+// fitErrorMessage summarizes the filter failures like the scheduler does.
+func fitErrorMessage(numNodes int, reasons map[string]int) string {
+	var reasonStrings []string
+	for reason, count := range reasons {
+		reasonStrings = append(reasonStrings, fmt.Sprintf("%d %s", count, reason))
+	}
+	slices.Sort(reasonStrings)
+	return fmt.Sprintf("0/%d nodes are available: %s.", numNodes, strings.Join(reasonStrings, ", "))
+}
@@ -0,0 +1,171 @@
+package simulator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	pluginconfig "github.com/coldzerofear/vgpu-manager-scheduler-plugin/pkg/apis/config"
+	"github.com/coldzerofear/vgpu-manager/pkg/device"
+	"github.com/coldzerofear/vgpu-manager/pkg/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_Simulator(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Simulator Test Suite")
+}
+
+const clusterTemplate = `
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Node
+  metadata:
+    name: gpu-node
+    annotations:
+      nvidia.com/node-device-heartbeat: "%s"
+      nvidia.com/node-config-info: '{"deviceSplit":10,"coresScaling":1,"memoryFactor":1,"memoryScaling":1}'
+      nvidia.com/node-device-register: '[{"id":0,"uuid":"GPU-0","core":100,"memory":10240,"number":10,"healthy":true},{"id":1,"uuid":"GPU-1","core":100,"memory":10240,"number":10,"healthy":true}]'
+  status:
+    allocatable:
+      nvidia.com/vgpu-number: "20"
+- apiVersion: v1
+  kind: Pod
+  metadata:
+    name: running
+    namespace: default
+    annotations:
+      nvidia.com/real-allocated: main[0_GPU-0_0_8192]
+  spec:
+    nodeName: gpu-node
+    containers:
+    - name: main
+      image: busybox
+      resources: {limits: {nvidia.com/vgpu-number: "1", nvidia.com/vgpu-memory: "8192"}}
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: ignored
+---
+apiVersion: v1
+kind: Pod
+metadata: {name: pending-0, namespace: default}
+spec:
+  containers:
+  - name: main
+    image: busybox
+    resources: {limits: {nvidia.com/vgpu-number: "1", nvidia.com/vgpu-memory: "8192"}}
+---
+apiVersion: v1
+kind: Pod
+metadata: {name: pending-1, namespace: default}
+spec:
+  containers:
+  - name: main
+    image: busybox
+    resources: {limits: {nvidia.com/vgpu-number: "1", nvidia.com/vgpu-memory: "4096"}}
+---
+apiVersion: v1
+kind: Pod
+metadata: {name: cpu-only, namespace: default}
+spec:
+  containers:
+  - name: main
+    image: busybox
+`
+
+var _ = Describe("Simulator", func() {
+	It("should load the nodes and pods of lists and documents", func() {
+		heartbeat, _ := metav1.NowMicro().MarshalText()
+		objects, err := decodeObjects(strings.NewReader(fmt.Sprintf(clusterTemplate, heartbeat)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(objects).To(HaveLen(5))
+		Expect(objects[0]).To(BeAssignableToTypeOf(&v1.Node{}))
+		Expect(objects[1].(*v1.Pod).Name).To(Equal("running"))
+	})
+
+	It("should schedule the pending pods on the remaining devices", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		heartbeat, _ := metav1.NowMicro().MarshalText()
+		objects, err := decodeObjects(strings.NewReader(fmt.Sprintf(clusterTemplate, heartbeat)))
+		Expect(err).NotTo(HaveOccurred())
+
+		simulator, err := New(ctx, objects, nil)
+		Expect(err).NotTo(HaveOccurred())
+		results, err := simulator.Run(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results).To(HaveLen(2))
+
+		Expect(results[0].Pod.Name).To(Equal("pending-0"))
+		Expect(results[0].Node).To(Equal("gpu-node"))
+		Expect(results[0].Devices).To(Equal(device.PodDevices{{
+			Name: "main", Devices: []device.ClaimDevice{{Id: 1, Uuid: "GPU-1", Memory: 8192}},
+		}}))
+		// Both GPUs are left with 2048Mi of memory.
+		Expect(results[1].Pod.Name).To(Equal("pending-1"))
+		Expect(results[1].Node).To(BeEmpty())
+		Expect(results[1].Reason).To(Equal("0/1 nodes are available: 1 insufficient GPU on node."))
+
+		out := &bytes.Buffer{}
+		Expect(PrintResults(out, results)).To(Succeed())
+		Expect(out.String()).To(Equal("" +
+			"NAMESPACE  POD        NODE      GPUS\n" +
+			"default    pending-0  gpu-node  main[1:GPU-1:0c/8192Mi]\n" +
+			"default    pending-1  <none>    0/1 nodes are available: 1 insufficient GPU on node.\n"))
+	})
+
+	It("should create the plugin once per simulation in the same process", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		heartbeat, _ := metav1.NowMicro().MarshalText()
+		objects, err := decodeObjects(strings.NewReader(fmt.Sprintf(clusterTemplate, heartbeat)))
+		Expect(err).NotTo(HaveOccurred())
+
+		for i := 0; i < 2; i++ {
+			_, err = New(ctx, objects, nil)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		_, err = New(ctx, objects, &pluginconfig.VGPUSchedulerPluginArgs{FeatureGates: map[string]bool{"GPUTopology": false}})
+		Expect(err).To(MatchError(ContainSubstring("all instances must configure the same features")))
+	})
+
+	It("should keep counting the pods allocating for a long time", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		heartbeat, _ := metav1.NowMicro().MarshalText()
+		objects, err := decodeObjects(strings.NewReader(fmt.Sprintf(clusterTemplate, heartbeat)))
+		Expect(err).NotTo(HaveOccurred())
+		allocating := objects[1].(*v1.Pod)
+		allocating.Labels = map[string]string{util.PodAssignedPhaseLabel: string(util.AssignPhaseAllocating)}
+		allocating.Annotations = map[string]string{
+			util.PodPredicateTimeAnnotation: fmt.Sprintf("%d", time.Now().Add(-time.Hour).UnixNano()),
+			util.PodVGPUPreAllocAnnotation:  "main[0_GPU-0_0_8192]",
+		}
+
+		// The args of the scheduler may enable the watchdog, which would check the pods every 500ms.
+		args := &pluginconfig.VGPUSchedulerPluginArgs{AllocationTimeout: &metav1.Duration{Duration: time.Second}}
+		simulator, err := New(ctx, objects, args)
+		Expect(err).NotTo(HaveOccurred())
+		Consistently(func() (string, error) {
+			pod, err := simulator.framework.ClientSet().CoreV1().Pods(allocating.Namespace).Get(ctx, allocating.Name, metav1.GetOptions{})
+			if err != nil {
+				return "", err
+			}
+			return pod.Labels[util.PodAssignedPhaseLabel], nil
+		}, 2*time.Second).Should(Equal(string(util.AssignPhaseAllocating)))
+		results, err := simulator.Run(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results).To(HaveLen(2))
+		Expect(results[0].Node).To(Equal("gpu-node"))
+		Expect(results[1].Node).To(BeEmpty())
+	})
+})